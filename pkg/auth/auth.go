@@ -0,0 +1,56 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth 实现 API Key 认证
+package auth
+
+import (
+	"errors"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/config"
+)
+
+var (
+	// ErrMissingKey 请求未携带 API Key
+	ErrMissingKey = errors.New("You didn't provide an API key. You need to provide your API key in an Authorization header using Bearer auth (i.e. Authorization: Bearer YOUR_KEY).")
+	// ErrInvalidKey API Key 无效
+	ErrInvalidKey = errors.New("Incorrect API key provided.")
+)
+
+// Authenticate 校验请求的 API Key
+// 先查找内联配置的 API Key，再查找 API Key 文件
+func Authenticate(cfg *config.AuthConfig, headers api.RequestHeaderMap) (*config.APIKeyConfig, error) {
+	apiKey := config.GetAPIKey(headers)
+	if apiKey == "" {
+		return nil, ErrMissingKey
+	}
+
+	keyHash := config.HashAPIKey(apiKey)
+	if key := cfg.FindKey(keyHash); key != nil {
+		return key, nil
+	}
+	if cfg.KeyFile != "" {
+		if key := getFileKeys(cfg.KeyFile).Find(keyHash); key != nil {
+			return key, nil
+		}
+	}
+	return nil, ErrInvalidKey
+}
+
+// Watch 开始监听 API Key 文件，配置解析时调用以便尽早发现文件错误
+func Watch(path string) error {
+	return getFileKeys(path).err()
+}
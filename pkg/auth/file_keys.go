@@ -0,0 +1,142 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/config"
+)
+
+const (
+	// EnvKeyFilePollInterval API Key 文件检查间隔
+	EnvKeyFilePollInterval = "AUTH_KEY_FILE_POLL_INTERVAL"
+	// DefaultKeyFilePollInterval 默认 API Key 文件检查间隔
+	DefaultKeyFilePollInterval = 5 * time.Second
+)
+
+var (
+	fileKeysMap = make(map[string]*fileKeys)
+	fileKeysMu  sync.Mutex
+)
+
+// fileKeys 从文件加载的 API Key
+// 定期检查文件修改时间，变化后重新加载；加载失败时保留上一次的内容
+type fileKeys struct {
+	mu      sync.RWMutex
+	path    string
+	keys    map[string]*config.APIKeyConfig
+	modTime time.Time
+	lastErr error
+}
+
+// getFileKeys 获取 API Key 文件，同一文件全局只监听一次
+func getFileKeys(path string) *fileKeys {
+	fileKeysMu.Lock()
+	defer fileKeysMu.Unlock()
+
+	if f, ok := fileKeysMap[path]; ok {
+		return f
+	}
+	f := &fileKeys{path: path}
+	f.reload()
+	fileKeysMap[path] = f
+	go f.watch(keyFilePollInterval())
+	return f
+}
+
+// Find 按 API Key 摘要查找
+func (f *fileKeys) Find(keyHash string) *config.APIKeyConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.keys[keyHash]
+}
+
+func (f *fileKeys) err() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.lastErr
+}
+
+// watch 定期检查文件是否变化
+func (f *fileKeys) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		f.reload()
+	}
+}
+
+// reload 文件修改时间变化时重新加载
+func (f *fileKeys) reload() {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		f.setErr(err)
+		return
+	}
+
+	f.mu.RLock()
+	unchanged := f.keys != nil && info.ModTime().Equal(f.modTime)
+	f.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		f.setErr(err)
+		return
+	}
+	var file struct {
+		Keys []*config.APIKeyConfig `json:"keys"`
+	}
+	if err := sonic.Unmarshal(data, &file); err != nil {
+		f.setErr(err)
+		return
+	}
+	keys, err := config.IndexAPIKeys(file.Keys)
+	if err != nil {
+		f.setErr(err)
+		return
+	}
+
+	f.mu.Lock()
+	f.keys = keys
+	f.modTime = info.ModTime()
+	f.lastErr = nil
+	f.mu.Unlock()
+	api.LogInfof("api key file %s loaded, keys=%d", f.path, len(keys))
+}
+
+func (f *fileKeys) setErr(err error) {
+	api.LogErrorf("load api key file %s failed, keep previous keys: %v", f.path, err)
+	f.mu.Lock()
+	f.lastErr = err
+	f.mu.Unlock()
+}
+
+func keyFilePollInterval() time.Duration {
+	if v := os.Getenv(EnvKeyFilePollInterval); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return DefaultKeyFilePollInterval
+}
@@ -0,0 +1,70 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/config"
+)
+
+// ErrMissingClaims 请求未携带已验证的 JWT
+var ErrMissingClaims = errors.New("You didn't provide a valid token. You need to provide your token in an Authorization header using Bearer auth (i.e. Authorization: Bearer YOUR_TOKEN).")
+
+// ExtractClaims 获取 Envoy jwt_authn 过滤器验证后的 JWT Claims
+// 优先读取 Dynamic Metadata，其次读取 Payload 请求头；都没有时返回 nil
+// jwt_authn 不会删除客户端传入的 Payload 请求头，只有 jwt_authn 写入了 Dynamic Metadata（即验证通过）时才信任该请求头，否则删除
+func ExtractClaims(cfg *config.JWTConfig, callbacks api.FilterCallbackHandler, headers api.RequestHeaderMap) (config.Claims, error) {
+	md := callbacks.StreamInfo().DynamicMetadata().Get(cfg.GetMetadataFilter())
+	if cfg.MetadataKey != "" && md != nil {
+		if payload, ok := md[cfg.MetadataKey].(map[string]interface{}); ok {
+			return config.Claims(payload), nil
+		}
+	}
+
+	if cfg.PayloadHeader != "" {
+		if value, ok := headers.Get(cfg.PayloadHeader); ok && value != "" {
+			if len(md) > 0 {
+				return decodePayload(value)
+			}
+			api.LogDebugf("jwt payload header %s not verified by %s, removed", cfg.PayloadHeader, cfg.GetMetadataFilter())
+			headers.Del(cfg.PayloadHeader)
+		}
+	}
+
+	if cfg.Required {
+		return nil, ErrMissingClaims
+	}
+	return nil, nil
+}
+
+// decodePayload 解码 Base64URL 编码的 JWT Payload
+func decodePayload(value string) (config.Claims, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt payload: %w", err)
+	}
+	var claims config.Claims
+	if err := sonic.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("invalid jwt payload: %w", err)
+	}
+	return claims, nil
+}
@@ -0,0 +1,194 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package completion 实现网关发起的 OpenAI 兼容 Chat Completions 旁路调用，用于分类模型和链式处理步骤
+package completion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+const (
+	// Path OpenAI 兼容接口路径
+	Path = "/v1/chat/completions"
+
+	// maxResponseSize 响应的最大长度
+	maxResponseSize = 1024 * 1024
+)
+
+var (
+	// ErrTimeout 调用超时
+	ErrTimeout = errors.New("completion timeout")
+
+	globalClient     *Client
+	globalClientOnce sync.Once
+)
+
+// Request 旁路调用请求
+type Request struct {
+	// TraceId 原请求的 Trace ID
+	TraceId string
+	// Host 选中的主机
+	Host types.Host
+	// Header 标识旁路流量的请求头，后端可据此区分
+	Header string
+	// HeaderValue 标识旁路流量的请求头值
+	HeaderValue string
+	// ModelName 模型名
+	ModelName string
+	// SystemPrompt system 提示词
+	SystemPrompt string
+	// UserPrompt 用户消息
+	UserPrompt string
+	// MaxTokens 最大输出 Token 数
+	MaxTokens int
+	// Temperature 采样温度，为空时使用模型默认值
+	Temperature *float64
+	// Timeout 超时时间
+	Timeout time.Duration
+}
+
+// Response 旁路调用结果
+type Response struct {
+	// Content 模型回答
+	Content string
+	// InputTokens 输入 token 数
+	InputTokens int
+	// OutputTokens 输出 token 数
+	OutputTokens int
+}
+
+// Client OpenAI 兼容接口客户端
+type Client struct {
+	httpClient *http.Client
+}
+
+// GetClient 获取全局客户端
+func GetClient() *Client {
+	globalClientOnce.Do(func() {
+		globalClient = &Client{httpClient: &http.Client{}}
+	})
+	return globalClient
+}
+
+// chatRequest 请求体
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	Stream      bool          `json:"stream"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatResponse 响应体
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// Call 发送非流式请求，返回模型回答和 Token 用量
+// 超时返回 ErrTimeout，ctx 取消时返回 context.Canceled；响应中有 usage 时即使失败也返回已消耗的 Token 数
+func (c *Client) Call(ctx context.Context, req *Request) (*Response, error) {
+	body, err := sonic.Marshal(&chatRequest{
+		Model: req.ModelName,
+		Messages: []chatMessage{
+			{Role: "system", Content: req.SystemPrompt},
+			{Role: "user", Content: req.UserPrompt},
+		},
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s%s", req.Host.Address(), Path)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	if req.Header != "" {
+		httpReq.Header.Set(req.Header, req.HeaderValue)
+	}
+	if req.TraceId != "" {
+		httpReq.Header.Set("x-request-id", req.TraceId)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, wrapError(err, req.Timeout)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, wrapError(err, req.Timeout)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model %s returned status %d", req.ModelName, resp.StatusCode)
+	}
+
+	var chatResp chatResponse
+	if err := sonic.Unmarshal(data, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse model %s response: %w", req.ModelName, err)
+	}
+
+	result := &Response{}
+	if chatResp.Usage != nil {
+		result.InputTokens = chatResp.Usage.PromptTokens
+		result.OutputTokens = chatResp.Usage.CompletionTokens
+	}
+	if len(chatResp.Choices) == 0 {
+		return result, fmt.Errorf("model %s returned no choices", req.ModelName)
+	}
+	result.Content = strings.TrimSpace(chatResp.Choices[0].Message.Content)
+	if result.Content == "" {
+		return result, fmt.Errorf("model %s returned empty content", req.ModelName)
+	}
+	return result, nil
+}
+
+// wrapError 将超时错误转换为 ErrTimeout
+func wrapError(err error, timeout time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// AuthConfig API Key 认证配置
+// 配置后所有请求都必须携带有效的 Authorization: Bearer <key>
+type AuthConfig struct {
+	// Keys 内联配置的 API Key
+	Keys []*APIKeyConfig `json:"keys,omitempty"`
+	// KeyFile API Key 文件路径，文件格式为 {"keys": [...]}，修改后自动重新加载
+	KeyFile string `json:"key_file,omitempty"`
+	// TenantHeader 认证成功后写入租户标识的请求头，默认 x-tenant-id，覆盖客户端传入的值
+	TenantHeader string `json:"tenant_header,omitempty"`
+
+	keys map[string]*APIKeyConfig
+}
+
+// APIKeyConfig 单个 API Key 的配置
+type APIKeyConfig struct {
+	// KeyHash API Key 的 SHA-256 摘要（十六进制），不保存明文
+	KeyHash string `json:"key_hash"`
+	// Tenant 租户标识
+	Tenant string `json:"tenant"`
+	// Models 允许访问的模型（model_mapping_rule 中的模型名），为空表示不限制
+	Models []string `json:"models,omitempty"`
+	// Headers 认证成功后注入的请求头，可用于 model_mapping_rule 的请求头匹配
+	Headers []*HeaderValue `json:"headers,omitempty"`
+	// Env 环境标识，需要配置 env
+	Env string `json:"env,omitempty"`
+	// Priority 请求优先级，需要配置 priority，未定义的优先级会被忽略
+	Priority string `json:"priority,omitempty"`
+}
+
+// GetTenantHeader 获取写入租户标识的请求头
+func (a *AuthConfig) GetTenantHeader() string {
+	if a == nil || a.TenantHeader == "" {
+		return DefaultTenantHeader
+	}
+	return a.TenantHeader
+}
+
+// FindKey 按 API Key 摘要查找内联配置的 API Key
+func (a *AuthConfig) FindKey(keyHash string) *APIKeyConfig {
+	if a == nil {
+		return nil
+	}
+	return a.keys[keyHash]
+}
+
+// AllowModel 判断 API Key 是否允许访问模型
+func (k *APIKeyConfig) AllowModel(model string) bool {
+	return len(k.Models) == 0 || slices.Contains(k.Models, model)
+}
+
+// IndexAPIKeys 校验 API Key 列表并按摘要建立索引
+func IndexAPIKeys(keys []*APIKeyConfig) (map[string]*APIKeyConfig, error) {
+	index := make(map[string]*APIKeyConfig, len(keys))
+	for i, key := range keys {
+		if key == nil || len(key.KeyHash) != 64 {
+			return nil, fmt.Errorf("invalid key_hash at index %d, sha256 hex digest is required", i)
+		}
+		if key.Tenant == "" {
+			return nil, fmt.Errorf("tenant is required for key at index %d", i)
+		}
+		if _, ok := index[key.KeyHash]; ok {
+			return nil, fmt.Errorf("duplicate key_hash at index %d", i)
+		}
+		index[key.KeyHash] = key
+	}
+	return index, nil
+}
+
+// validate 验证认证配置并建立内联 API Key 索引
+func (a *AuthConfig) validate() error {
+	if len(a.Keys) == 0 && a.KeyFile == "" {
+		return errors.New("keys or key_file is required")
+	}
+	keys, err := IndexAPIKeys(a.Keys)
+	if err != nil {
+		return err
+	}
+	a.keys = keys
+	return nil
+}
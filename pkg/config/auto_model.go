@@ -0,0 +1,317 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// AutoReasonDefault 没有分类器匹配时的路由原因
+	AutoReasonDefault = "default"
+	// AutoReasonLLMPrefix 分类模型选择时的路由原因前缀，后接分类标签
+	AutoReasonLLMPrefix = "llm:"
+
+	// DefaultLLMClassifierTimeout 默认分类模型调用超时时间
+	DefaultLLMClassifierTimeout = 500 * time.Millisecond
+	// DefaultLLMClassifierCacheTTL 默认分类结果缓存时间
+	DefaultLLMClassifierCacheTTL = 5 * time.Minute
+	// DefaultLLMClassifierMaxPromptLength 默认发送给分类模型的 Prompt 最大长度（字节）
+	DefaultLLMClassifierMaxPromptLength = 4096
+)
+
+// codeFence Markdown 代码块标记
+var codeFence = []byte("```")
+
+// AutoModelConfig 虚拟模型配置
+// 客户端请求虚拟模型（如 auto）时，按分类器从 Prompt 文本中选择实际模型，再按实际模型正常路由
+type AutoModelConfig struct {
+	// Classifiers 分类器，按顺序匹配，第一个匹配的分类器决定实际模型
+	Classifiers []*Classifier `json:"classifiers"`
+	// Default 没有分类器匹配时使用的模型，分类模型超时或失败时也使用该模型
+	Default string `json:"default"`
+	// LLM 分类模型配置，规则分类器都不匹配时调用
+	LLM *LLMClassifierConfig `json:"llm,omitempty"`
+}
+
+// LLMClassifierConfig 分类模型配置
+// 调用 model_mapping_rule 中的小模型（OpenAI 兼容接口）对 Prompt 分类，按返回的标签选择实际模型
+type LLMClassifierConfig struct {
+	// Model 分类模型，必须能匹配 model_mapping_rule
+	Model string `json:"model"`
+	// Prompt 路由提示词（system 消息），为空时按标签和描述生成
+	// 自定义提示词需要要求模型只返回 {"label": "<标签>"}
+	Prompt string `json:"prompt,omitempty"`
+	// Labels 分类标签到模型的映射
+	Labels []*LabelRule `json:"labels"`
+	// TimeoutMs 调用超时时间（毫秒），超时使用 default 模型
+	TimeoutMs int64 `json:"timeout_ms,omitempty"`
+	// CacheTTLSeconds 按 Prompt 哈希缓存分类结果的时间（秒）
+	CacheTTLSeconds int64 `json:"cache_ttl_seconds,omitempty"`
+	// MaxPromptLength 发送给分类模型的 Prompt 最大长度（字节），超过时保留末尾部分
+	MaxPromptLength int `json:"max_prompt_length,omitempty"`
+
+	// labels 归一化的标签到模型的映射，Parse 时生成
+	labels map[string]string
+}
+
+// LabelRule 分类标签到模型的映射
+type LabelRule struct {
+	// Label 分类标签
+	Label string `json:"label"`
+	// Model 选择的模型
+	Model string `json:"model"`
+	// Description 标签描述，用于生成路由提示词
+	Description string `json:"description,omitempty"`
+}
+
+// GetTimeout 获取分类模型调用超时时间
+func (l *LLMClassifierConfig) GetTimeout() time.Duration {
+	if l.TimeoutMs > 0 {
+		return time.Duration(l.TimeoutMs) * time.Millisecond
+	}
+	return DefaultLLMClassifierTimeout
+}
+
+// GetCacheTTL 获取分类结果缓存时间
+func (l *LLMClassifierConfig) GetCacheTTL() time.Duration {
+	if l.CacheTTLSeconds > 0 {
+		return time.Duration(l.CacheTTLSeconds) * time.Second
+	}
+	return DefaultLLMClassifierCacheTTL
+}
+
+// TruncatePrompt 截取发送给分类模型的 Prompt，保留末尾（最新消息）部分
+func (l *LLMClassifierConfig) TruncatePrompt(prompt []byte) []byte {
+	limit := l.MaxPromptLength
+	if limit <= 0 {
+		limit = DefaultLLMClassifierMaxPromptLength
+	}
+	if len(prompt) <= limit {
+		return prompt
+	}
+	prompt = prompt[len(prompt)-limit:]
+	// 跳过被截断的 UTF-8 字符
+	for len(prompt) > 0 && !utf8.RuneStart(prompt[0]) {
+		prompt = prompt[1:]
+	}
+	return prompt
+}
+
+// SystemPrompt 获取路由提示词
+func (l *LLMClassifierConfig) SystemPrompt() string {
+	if l.Prompt != "" {
+		return l.Prompt
+	}
+	var b strings.Builder
+	b.WriteString("You are a request router. Classify the user's request into exactly one of the following labels:\n")
+	for _, rule := range l.Labels {
+		b.WriteString("- ")
+		b.WriteString(rule.Label)
+		if rule.Description != "" {
+			b.WriteString(": ")
+			b.WriteString(rule.Description)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(`Respond with JSON only, in the form {"label": "<label>"}.`)
+	return b.String()
+}
+
+// ModelForLabel 获取分类标签对应的模型，标签不区分大小写
+func (l *LLMClassifierConfig) ModelForLabel(label string) (string, bool) {
+	model, ok := l.labels[normalizeLabel(label)]
+	return model, ok
+}
+
+// validate 验证分类模型配置
+func (l *LLMClassifierConfig) validate() error {
+	if l.Model == "" {
+		return errors.New("llm model is required")
+	}
+	if len(l.Labels) == 0 {
+		return errors.New("llm labels are required")
+	}
+	if l.TimeoutMs < 0 || l.CacheTTLSeconds < 0 || l.MaxPromptLength < 0 {
+		return errors.New("llm timeout_ms, cache_ttl_seconds and max_prompt_length must be non-negative")
+	}
+	l.labels = make(map[string]string, len(l.Labels))
+	for _, rule := range l.Labels {
+		label := normalizeLabel(rule.Label)
+		if label == "" || rule.Model == "" {
+			return errors.New("llm label and model are required")
+		}
+		if _, ok := l.labels[label]; ok {
+			return fmt.Errorf("duplicate llm label %s", rule.Label)
+		}
+		l.labels[label] = rule.Model
+	}
+	return nil
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
+
+// Classifier 基于规则的 Prompt 分类器，所有配置的条件都满足时匹配
+type Classifier struct {
+	// Name 分类器名称，作为路由原因记录在日志和响应头中
+	Name string `json:"name"`
+	// Model 匹配时选择的模型
+	Model string `json:"model"`
+	// Keywords 关键词，Prompt 包含任一关键词即满足（不区分大小写）
+	Keywords []string `json:"keywords,omitempty"`
+	// Regex 正则表达式（RE2 语法），Prompt 匹配任一正则即满足
+	Regex []string `json:"regex,omitempty"`
+	// PromptLength Prompt 文本长度（字节）范围
+	PromptLength *Range `json:"prompt_length,omitempty"`
+	// HasCode 是否包含 Markdown 代码块
+	HasCode *bool `json:"has_code,omitempty"`
+
+	// keywords 转为小写的关键词，Parse 时生成
+	keywords [][]byte
+	// regex 编译后的正则，Parse 时生成
+	regex []*regexp.Regexp
+}
+
+// Classify 按规则分类器选择实际模型，返回模型和路由原因
+// 没有分类器匹配时返回 default 模型；配置了分类模型时返回空模型名，由调用方调用分类模型
+func (a *AutoModelConfig) Classify(prompt []byte) (string, string) {
+	var lower []byte
+	for _, c := range a.Classifiers {
+		if len(c.keywords) > 0 && lower == nil {
+			lower = bytes.ToLower(prompt)
+		}
+		if c.match(prompt, lower) {
+			return c.Model, c.Name
+		}
+	}
+	if a.LLM != nil {
+		return "", ""
+	}
+	return a.Default, AutoReasonDefault
+}
+
+// match 判断 Prompt 是否满足分类器的所有条件
+func (c *Classifier) match(prompt, lower []byte) bool {
+	if len(c.keywords) > 0 {
+		matched := false
+		for _, kw := range c.keywords {
+			if bytes.Contains(lower, kw) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(c.regex) > 0 {
+		matched := false
+		for _, re := range c.regex {
+			if re.Match(prompt) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if c.PromptLength != nil && !c.PromptLength.Contains(int64(len(prompt))) {
+		return false
+	}
+	if c.HasCode != nil && *c.HasCode != bytes.Contains(prompt, codeFence) {
+		return false
+	}
+	return true
+}
+
+// compile 验证分类器并编译关键词和正则
+func (c *Classifier) compile() error {
+	if c.Name == "" || c.Model == "" {
+		return errors.New("name and model are required")
+	}
+	if len(c.Keywords) == 0 && len(c.Regex) == 0 && c.PromptLength == nil && c.HasCode == nil {
+		return fmt.Errorf("classifier %s has no conditions", c.Name)
+	}
+	if c.PromptLength != nil {
+		if err := c.PromptLength.validate(); err != nil {
+			return fmt.Errorf("classifier %s: prompt_length: %v", c.Name, err)
+		}
+	}
+
+	c.keywords = make([][]byte, 0, len(c.Keywords))
+	for _, kw := range c.Keywords {
+		c.keywords = append(c.keywords, bytes.ToLower([]byte(kw)))
+	}
+	c.regex = make([]*regexp.Regexp, 0, len(c.Regex))
+	for _, expr := range c.Regex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("classifier %s: invalid regex %s: %v", c.Name, expr, err)
+		}
+		c.regex = append(c.regex, re)
+	}
+	return nil
+}
+
+// FindAutoModel 查找虚拟模型配置
+func (c *LLMProxyConfig) FindAutoModel(modelName string) *AutoModelConfig {
+	if c.AutoModelRule == nil || modelName == "" {
+		return nil
+	}
+	return c.AutoModelRule[modelName]
+}
+
+// validateAutoModels 验证虚拟模型配置，分类器选择的模型必须能匹配 model_mapping_rule
+func (c *LLMProxyConfig) validateAutoModels() error {
+	for name, auto := range c.AutoModelRule {
+		if _, ok := c.GetModelMappingRule()[name]; ok {
+			return fmt.Errorf("model=%s, err=auto model conflicts with model_mapping_rule", name)
+		}
+		if auto.Default == "" {
+			return fmt.Errorf("model=%s, err=default is required", name)
+		}
+		models := []string{auto.Default}
+		for _, classifier := range auto.Classifiers {
+			if err := classifier.compile(); err != nil {
+				return fmt.Errorf("model=%s, err=%v", name, err)
+			}
+			models = append(models, classifier.Model)
+		}
+		if auto.LLM != nil {
+			if err := auto.LLM.validate(); err != nil {
+				return fmt.Errorf("model=%s, err=%v", name, err)
+			}
+			models = append(models, auto.LLM.Model)
+			for _, rule := range auto.LLM.Labels {
+				models = append(models, rule.Model)
+			}
+		}
+		for _, model := range models {
+			if c.MatchModel(model) == nil {
+				return fmt.Errorf("model=%s, err=model %s not found in model_mapping_rule", name, model)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ChainOutputReplace 步骤输出替换最后一条用户消息的文本
+	ChainOutputReplace = "replace"
+	// ChainOutputSystem 步骤输出作为 system 消息插入最后一条用户消息之前
+	ChainOutputSystem = "system"
+
+	// ChainOnErrorSkip 步骤失败时跳过该步骤，使用原来的输入继续执行
+	ChainOnErrorSkip = "skip"
+	// ChainOnErrorAbort 步骤失败时终止请求
+	ChainOnErrorAbort = "abort"
+
+	// DefaultChainStepTimeout 默认步骤超时时间
+	DefaultChainStepTimeout = 10 * time.Second
+	// DefaultChainStepMaxTokens 默认步骤最大输出 Token 数
+	DefaultChainStepMaxTokens = 1024
+)
+
+// ChainConfig 链式处理配置
+// 规则配置 chain_name 时，在网关内按顺序执行预处理步骤，改写请求后再转发给规则的主模型
+type ChainConfig struct {
+	// Steps 预处理步骤，按顺序执行
+	Steps []*ChainStep `json:"steps"`
+}
+
+// ChainStep 链式处理的预处理步骤
+// 以最后一条用户消息的文本为输入，调用 model_mapping_rule 中的模型（OpenAI 兼容接口）
+type ChainStep struct {
+	// Name 步骤名称
+	Name string `json:"name"`
+	// Model 步骤调用的模型，必须能匹配 model_mapping_rule
+	Model string `json:"model"`
+	// Prompt 步骤的 system 提示词，如 "Rewrite the user's query for search."
+	Prompt string `json:"prompt"`
+	// Output 步骤输出的使用方式（replace、system），默认 replace
+	Output string `json:"output,omitempty"`
+	// TimeoutMs 步骤超时时间（毫秒）
+	TimeoutMs int64 `json:"timeout_ms,omitempty"`
+	// MaxTokens 步骤最大输出 Token 数
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// OnError 步骤失败时的处理方式（skip、abort），默认 skip
+	OnError string `json:"on_error,omitempty"`
+}
+
+// GetOutput 获取步骤输出的使用方式
+func (s *ChainStep) GetOutput() string {
+	if s.Output == "" {
+		return ChainOutputReplace
+	}
+	return s.Output
+}
+
+// GetTimeout 获取步骤超时时间
+func (s *ChainStep) GetTimeout() time.Duration {
+	if s.TimeoutMs > 0 {
+		return time.Duration(s.TimeoutMs) * time.Millisecond
+	}
+	return DefaultChainStepTimeout
+}
+
+// GetMaxTokens 获取步骤最大输出 Token 数
+func (s *ChainStep) GetMaxTokens() int {
+	if s.MaxTokens > 0 {
+		return s.MaxTokens
+	}
+	return DefaultChainStepMaxTokens
+}
+
+// GetOnError 获取步骤失败时的处理方式
+func (s *ChainStep) GetOnError() string {
+	if s.OnError == "" {
+		return ChainOnErrorSkip
+	}
+	return s.OnError
+}
+
+// validate 验证步骤配置
+func (s *ChainStep) validate() error {
+	if s.Name == "" || s.Model == "" || s.Prompt == "" {
+		return errors.New("name, model and prompt are required")
+	}
+	switch s.GetOutput() {
+	case ChainOutputReplace, ChainOutputSystem:
+	default:
+		return fmt.Errorf("step %s: unknown output %s", s.Name, s.Output)
+	}
+	switch s.GetOnError() {
+	case ChainOnErrorSkip, ChainOnErrorAbort:
+	default:
+		return fmt.Errorf("step %s: unknown on_error %s", s.Name, s.OnError)
+	}
+	if s.TimeoutMs < 0 || s.MaxTokens < 0 {
+		return fmt.Errorf("step %s: timeout_ms and max_tokens must be non-negative", s.Name)
+	}
+	return nil
+}
+
+// FindChain 查找链式处理配置
+func (c *LLMProxyConfig) FindChain(chainName string) *ChainConfig {
+	if c.Chains == nil || chainName == "" {
+		return nil
+	}
+	return c.Chains[chainName]
+}
+
+// validateChains 验证链式处理配置，规则引用的链必须存在，步骤调用的模型必须能匹配 model_mapping_rule
+func (c *LLMProxyConfig) validateChains() error {
+	for name, chain := range c.Chains {
+		if chain == nil || len(chain.Steps) == 0 {
+			return fmt.Errorf("chain=%s, err=steps is empty", name)
+		}
+		for _, step := range chain.Steps {
+			if err := step.validate(); err != nil {
+				return fmt.Errorf("chain=%s, err=%v", name, err)
+			}
+			if c.MatchModel(step.Model) == nil {
+				return fmt.Errorf("chain=%s, err=model %s not found in model_mapping_rule", name, step.Model)
+			}
+		}
+	}
+
+	check := func(scope string, mappingRules map[string]*Rules) error {
+		for key, rule := range mappingRules {
+			for _, r := range rule.GetRules() {
+				if r.ChainName != "" && c.FindChain(r.ChainName) == nil {
+					return fmt.Errorf("%smodel=%s, err=chain %s not found", scope, key, r.ChainName)
+				}
+			}
+		}
+		return nil
+	}
+	if err := check("", c.GetModelMappingRule()); err != nil {
+		return err
+	}
+	for env, mappingRules := range c.EnvModelMappingRule {
+		if err := check("env="+env+", ", mappingRules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,8 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
-
-	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+	"time"
 
 	"github.com/istio-llm-filter/pkg/types"
 )
@@ -37,6 +36,26 @@ type Config struct {
 	LbMappingRule map[string]*LBConfig `json:"lb_mapping_rule"`
 	// Log 日志配置
 	Log *LogConfig `json:"log,omitempty"`
+	// Auth API Key 认证配置
+	Auth *AuthConfig `json:"auth,omitempty"`
+	// JWT JWT Claims 配置
+	JWT *JWTConfig `json:"jwt,omitempty"`
+	// Priority 请求优先级配置
+	Priority *PriorityConfig `json:"priority,omitempty"`
+	// RateLimitRule 模型到限流配置的映射
+	RateLimitRule map[string]*RateLimitConfig `json:"rate_limit_rule,omitempty"`
+	// QuotaRule 模型到租户额度配置的映射
+	QuotaRule map[string]*QuotaConfig `json:"quota_rule,omitempty"`
+	// Env 环境标识配置
+	Env *EnvConfig `json:"env,omitempty"`
+	// EnvModelMappingRule 环境到模型路由规则映射，覆盖 model_mapping_rule 中同一模型的规则
+	EnvModelMappingRule map[string]map[string]*Rules `json:"env_model_mapping_rule,omitempty"`
+	// Chains 链名称到链式处理配置的映射，规则通过 chain_name 引用
+	Chains map[string]*ChainConfig `json:"chains,omitempty"`
+	// AutoModelRule 虚拟模型到分类器配置的映射
+	AutoModelRule map[string]*AutoModelConfig `json:"auto_model_rule,omitempty"`
+	// SessionHeader 会话标识请求头，用于分流等需要同一会话保持一致的场景
+	SessionHeader string `json:"session_header,omitempty"`
 }
 
 // GetProtocol 获取协议
@@ -62,6 +81,16 @@ func (c *Config) GetLbMappingRule() map[string]*LBConfig {
 	return c.LbMappingRule
 }
 
+// GetRateLimitRule 获取限流配置映射
+func (c *Config) GetRateLimitRule() map[string]*RateLimitConfig {
+	return c.RateLimitRule
+}
+
+// GetQuotaRule 获取租户额度配置映射
+func (c *Config) GetQuotaRule() map[string]*QuotaConfig {
+	return c.QuotaRule
+}
+
 // GetLog 获取日志配置
 func (c *Config) GetLog() *LogConfig {
 	return c.Log
@@ -70,6 +99,10 @@ func (c *Config) GetLog() *LogConfig {
 // Rules 规则列表（用于支持 map 中的 repeated 值）
 type Rules struct {
 	Rules []*Rule `json:"rules"`
+	// Fallbacks 降级目标，按顺序尝试
+	Fallbacks []*Fallback `json:"fallbacks,omitempty"`
+	// Mirror 请求镜像配置
+	Mirror *MirrorConfig `json:"mirror,omitempty"`
 }
 
 // GetRules 获取规则列表
@@ -84,18 +117,28 @@ func (r *Rules) GetRules() []*Rule {
 type Rule struct {
 	// SceneName 场景名称
 	SceneName string `json:"scene_name"`
-	// ChainName 链名称
+	// ChainName 链名称，引用 chains 中的链式处理配置
 	ChainName string `json:"chain_name"`
 	// Backend 后端协议类型 (vllm, sglang, triton)
 	Backend string `json:"backend"`
 	// RouteName 路由名称
 	RouteName string `json:"route_name"`
 	// Headers 请求头匹配条件
-	Headers []*HeaderValue `json:"headers,omitempty"`
+	Headers []*ValueMatcher `json:"headers,omitempty"`
+	// QueryParams 查询参数匹配条件
+	QueryParams []*ValueMatcher `json:"query_params,omitempty"`
+	// Claims JWT Claims 匹配条件，需要配置 jwt
+	Claims []*ClaimValue `json:"claims,omitempty"`
+	// Content 请求内容匹配条件
+	Content *ContentMatch `json:"content,omitempty"`
 	// Subset 后端子集过滤
 	Subset []*Subset `json:"subset,omitempty"`
 	// Cluster 集群名称
 	Cluster string `json:"cluster"`
+	// Priority 匹配该规则的请求的默认优先级
+	Priority string `json:"priority,omitempty"`
+	// Order 规则的匹配顺序，值小的先匹配，相同时匹配条件多的先匹配
+	Order int32 `json:"order,omitempty"`
 }
 
 // HeaderValue 请求头键值对
@@ -112,12 +155,22 @@ type Subset struct {
 	Labels map[string]string `json:"labels,omitempty"`
 	// Lora LoRA 适配器名称
 	Lora string `json:"lora,omitempty"`
-	// Weight 权重
+	// LoraPath LoRA 适配器源路径，配置后会在所选主机未加载该适配器时动态加载
+	LoraPath string `json:"lora_path,omitempty"`
+	// LoraLoadTimeoutMs LoRA 适配器动态加载超时时间（毫秒）
+	LoraLoadTimeoutMs int32 `json:"lora_load_timeout_ms,omitempty"`
+	// Weight 分流权重，任一子集配置权重时按权重选择一个子集
 	Weight int32 `json:"weight,omitempty"`
+	// Cluster 分流到的集群，为空时使用规则的集群
+	Cluster string `json:"cluster,omitempty"`
+	// Backend 分流到的后端协议类型，为空时使用规则的后端
+	Backend string `json:"backend,omitempty"`
 }
 
 // LBConfig 负载均衡配置
 type LBConfig struct {
+	// Algorithm 负载均衡算法，为空时使用全局配置
+	Algorithm string `json:"algorithm,omitempty"`
 	// LoadAwareEnable 是否启用负载感知
 	LoadAwareEnable bool `json:"load_aware_enable"`
 	// CacheAwareEnable 是否启用缓存感知
@@ -130,6 +183,161 @@ type LBConfig struct {
 	PrefillLoadWeight int32 `json:"prefill_load_weight"`
 	// CacheRadioWeight 缓存命中率权重
 	CacheRadioWeight int32 `json:"cache_radio_weight"`
+	// KVUsageWeight KV-Cache 使用率惩罚权重
+	KVUsageWeight int32 `json:"kv_usage_weight"`
+	// KVUsageThreshold KV-Cache 使用率排除阈值 (0-100)，0 表示不排除
+	KVUsageThreshold int32 `json:"kv_usage_threshold"`
+	// LoraAffinityWeight 已加载所需 LoRA 适配器的主机评分加成权重
+	LoraAffinityWeight int32 `json:"lora_affinity_weight"`
+	// MaxAdaptersPerHost 单主机最多加载的 LoRA 适配器数量，0 表示不限制
+	MaxAdaptersPerHost int32 `json:"max_adapters_per_host"`
+	// LoraPollIntervalMs 轮询后端 /v1/models 获取已加载 LoRA 适配器的间隔（毫秒），0 表示不轮询
+	LoraPollIntervalMs int32 `json:"lora_poll_interval_ms,omitempty"`
+	// SelectStrategy 候选主机选择策略 (top_percent, score_gap, softmax)，默认 top_percent
+	SelectStrategy string `json:"select_strategy,omitempty"`
+	// SoftmaxTemperature softmax 选择策略温度，越低越集中于高分主机
+	SoftmaxTemperature float64 `json:"softmax_temperature,omitempty"`
+	// ScoreGap score_gap 选择策略的评分差距阈值
+	ScoreGap float64 `json:"score_gap,omitempty"`
+	// MinCandidates 最小候选数量
+	MinCandidates int32 `json:"min_candidates,omitempty"`
+	// SLO 延迟目标
+	SLO *SLOConfig `json:"slo,omitempty"`
+	// Pipeline 过滤和评分插件流水线，配置后替代上述整数权重
+	Pipeline *types.LbPipelineConfig `json:"pipeline,omitempty"`
+	// PrefillHeader PD 分离模式下传递 Prefill 主机地址的请求头
+	PrefillHeader string `json:"prefill_header,omitempty"`
+	// PrefillBodyField PD 分离模式下传递 Prefill 主机地址的请求体字段，为空时不写入请求体
+	PrefillBodyField string `json:"prefill_body_field,omitempty"`
+	// Queue 后端饱和时的网关排队配置，为空时不排队
+	Queue *QueueConfig `json:"queue,omitempty"`
+	// Affinity 会话亲和配置，为空时不启用
+	Affinity *AffinityConfig `json:"affinity,omitempty"`
+}
+
+// DefaultAffinityTTLSeconds 默认会话亲和记录的有效期（秒）
+const DefaultAffinityTTLSeconds = 1800
+
+// AffinityConfig 会话亲和配置
+// 记录会话上一次选中的主机，主机仍可用且负载未超过上限时优先选择该主机
+type AffinityConfig struct {
+	// MaxQueueDepth 亲和主机未完成请求数达到该值时按正常评分选择，默认 16
+	MaxQueueDepth int32 `json:"max_queue_depth,omitempty"`
+	// TTLSeconds 会话亲和记录的有效期（秒），默认 1800
+	TTLSeconds int32 `json:"ttl_seconds,omitempty"`
+}
+
+// GetMaxQueueDepth 获取亲和主机的未完成请求数上限
+func (a *AffinityConfig) GetMaxQueueDepth() int {
+	if a.MaxQueueDepth <= 0 {
+		return types.DefaultAffinityMaxQueueDepth
+	}
+	return int(a.MaxQueueDepth)
+}
+
+// GetTTL 获取会话亲和记录的有效期
+func (a *AffinityConfig) GetTTL() time.Duration {
+	if a.TTLSeconds <= 0 {
+		return DefaultAffinityTTLSeconds * time.Second
+	}
+	return time.Duration(a.TTLSeconds) * time.Second
+}
+
+// DefaultTenantHeader 默认的租户标识请求头
+const DefaultTenantHeader = "x-tenant-id"
+
+// QueueConfig 网关排队配置
+type QueueConfig struct {
+	// HostQueueDepth 主机未完成请求数达到该值视为饱和，所有候选主机饱和时请求进入网关排队
+	HostQueueDepth int32 `json:"host_queue_depth"`
+	// MaxSize 队列最大长度，超过后返回 429
+	MaxSize int32 `json:"max_size,omitempty"`
+	// MaxWaitMs 最长排队时间（毫秒），超时返回 503
+	MaxWaitMs int32 `json:"max_wait_ms,omitempty"`
+	// DispatchIntervalMs 出队检查间隔（毫秒）
+	DispatchIntervalMs int32 `json:"dispatch_interval_ms,omitempty"`
+	// TenantHeader 租户标识请求头，默认 x-tenant-id
+	TenantHeader string `json:"tenant_header,omitempty"`
+	// TenantWeights 租户公平调度权重，未配置的租户权重为 1
+	TenantWeights map[string]int32 `json:"tenant_weights,omitempty"`
+}
+
+// GetTenantHeader 获取租户标识请求头
+func (q *QueueConfig) GetTenantHeader() string {
+	if q == nil || q.TenantHeader == "" {
+		return DefaultTenantHeader
+	}
+	return q.TenantHeader
+}
+
+// GetTenantWeight 获取租户公平调度权重
+func (q *QueueConfig) GetTenantWeight(tenant string) int {
+	if q == nil {
+		return 1
+	}
+	if weight, ok := q.TenantWeights[tenant]; ok && weight > 0 {
+		return int(weight)
+	}
+	return 1
+}
+
+// SLO 无法满足时的处理策略
+const (
+	// SLOPolicyNone 仍然路由到评分最高的主机
+	SLOPolicyNone = "none"
+	// SLOPolicyOverflow 路由到溢出集群
+	SLOPolicyOverflow = "overflow"
+	// SLOPolicyReject 返回 429
+	SLOPolicyReject = "reject"
+)
+
+// SLOConfig 延迟目标配置
+type SLOConfig struct {
+	// TTFTMs 首 Token 延迟目标（毫秒）
+	TTFTMs int32 `json:"ttft_ms,omitempty"`
+	// TPOTMs 每个输出 Token 的延迟目标（毫秒）
+	TPOTMs int32 `json:"tpot_ms,omitempty"`
+	// Policy 没有主机能满足 SLO 时的处理策略 (none, overflow, reject)，默认 none
+	Policy string `json:"policy,omitempty"`
+	// OverflowCluster 溢出集群，Policy 为 overflow 时使用
+	OverflowCluster string `json:"overflow_cluster,omitempty"`
+	// RetryAfterSeconds 拒绝时返回的 Retry-After 秒数
+	RetryAfterSeconds int32 `json:"retry_after_seconds,omitempty"`
+}
+
+// GetPolicy 获取 SLO 无法满足时的处理策略
+func (s *SLOConfig) GetPolicy() string {
+	if s == nil || s.Policy == "" {
+		return SLOPolicyNone
+	}
+	return s.Policy
+}
+
+// GetRetryAfterSeconds 获取 Retry-After 秒数，默认 1 秒
+func (s *SLOConfig) GetRetryAfterSeconds() int {
+	if s == nil || s.RetryAfterSeconds <= 0 {
+		return 1
+	}
+	return int(s.RetryAfterSeconds)
+}
+
+// DefaultPrefillHeader 默认传递 Prefill 主机地址的请求头
+const DefaultPrefillHeader = "x-prefiller-host-port"
+
+// GetPrefillHeader 获取传递 Prefill 主机地址的请求头
+func (l *LBConfig) GetPrefillHeader() string {
+	if l == nil || l.PrefillHeader == "" {
+		return DefaultPrefillHeader
+	}
+	return l.PrefillHeader
+}
+
+// GetPrefillBodyField 获取传递 Prefill 主机地址的请求体字段
+func (l *LBConfig) GetPrefillBodyField() string {
+	if l == nil {
+		return ""
+	}
+	return l.PrefillBodyField
 }
 
 // LogConfig 日志配置
@@ -161,17 +369,25 @@ type Tuple struct {
 	TargetModel *Rule
 }
 
-// SortTuples 按 Headers 长度降序排序
-// 确保更具体的匹配规则优先
+// SortTuples 按 Order 升序排序，Order 相同时匹配条件多的规则优先，都相同时保持配置顺序
+// 没有规则配置 Order 时与按请求头数量排序的行为一致，没有匹配条件的默认规则总是最后匹配
 func SortTuples(tuples []Tuple) {
-	sort.Slice(tuples, func(i, j int) bool {
-		return len(tuples[i].TargetModel.Headers) > len(tuples[j].TargetModel.Headers)
+	sort.SliceStable(tuples, func(i, j int) bool {
+		a, b := tuples[i].TargetModel, tuples[j].TargetModel
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return a.conditionCount() > b.conditionCount()
 	})
 }
 
 // Mapping 模型到规则的映射
 type Mapping struct {
 	Tuples []Tuple
+	// Fallbacks 降级目标
+	Fallbacks []*Fallback
+	// Mirror 请求镜像配置
+	Mirror *MirrorConfig
 }
 
 // LLMProxyConfig 完整的 LLM Proxy 配置
@@ -179,6 +395,10 @@ type LLMProxyConfig struct {
 	Config
 	// ModelMappings 解析后的模型映射
 	ModelMappings map[string]*Mapping
+	// modelPatterns 通配和正则模型名规则，按匹配顺序排列
+	modelPatterns []*modelPattern
+	// envModelMappings 解析后的各环境模型映射
+	envModelMappings map[string]map[string]*Mapping
 	// LbMappingConfigs 解析后的负载均衡配置
 	LbMappingConfigs map[string]*LBConfig
 	// MC Metadata-Center 客户端
@@ -201,7 +421,9 @@ func buildModelMappings(mappingRules map[string]*Rules) map[string]*Mapping {
 		}
 		SortTuples(tuples)
 		mappings[model] = &Mapping{
-			Tuples: tuples,
+			Tuples:    tuples,
+			Fallbacks: r.Fallbacks,
+			Mirror:    r.Mirror,
 		}
 	}
 	return mappings
@@ -212,6 +434,14 @@ func (c *LLMProxyConfig) Init() error {
 	mappingRules := c.GetModelMappingRule()
 	if len(mappingRules) > 0 {
 		c.ModelMappings = buildModelMappings(mappingRules)
+		patterns, err := buildModelPatterns(mappingRules)
+		if err != nil {
+			return err
+		}
+		c.modelPatterns = patterns
+	}
+	if len(c.EnvModelMappingRule) > 0 {
+		c.envModelMappings = buildEnvModelMappings(c.EnvModelMappingRule)
 	}
 	lbMappingConfigs := c.GetLbMappingRule()
 	if len(lbMappingConfigs) > 0 {
@@ -228,34 +458,71 @@ func (c *LLMProxyConfig) FindLbMappingRule(modelName string) *LBConfig {
 	return c.LbMappingConfigs[modelName]
 }
 
+// FindRateLimitRule 查找模型对应的限流配置
+func (c *LLMProxyConfig) FindRateLimitRule(modelName string) *RateLimitConfig {
+	if c.RateLimitRule == nil || modelName == "" {
+		return nil
+	}
+	return c.RateLimitRule[modelName]
+}
+
+// FindQuotaRule 查找模型对应的租户额度配置
+func (c *LLMProxyConfig) FindQuotaRule(modelName string) *QuotaConfig {
+	if c.QuotaRule == nil || modelName == "" {
+		return nil
+	}
+	return c.QuotaRule[modelName]
+}
+
+// FindAlgorithm 查找模型使用的负载均衡算法
+// 优先使用模型的负载均衡配置，否则使用全局配置
+func (c *LLMProxyConfig) FindAlgorithm(modelName string) string {
+	if lbConfig := c.FindLbMappingRule(modelName); lbConfig != nil && lbConfig.Algorithm != "" {
+		return lbConfig.Algorithm
+	}
+	return c.GetAlgorithm()
+}
+
 // validateRules 验证规则数组
 // 同一模型下的所有规则必须满足：
-// 1. cluster 必须一致
-// 2. backend 可以为空，默认为 triton
-// 3. backend 必须一致
-func validateRules(rules []*Rule) (string, string, error) {
+// 1. backend 可以为空，默认为 triton
+// 2. 分流配置合法，每个规则或分流子集都有 cluster
+// 3. 匹配条件合法，没有匹配条件的规则必须最后匹配
+func validateRules(rules []*Rule) error {
 	if len(rules) == 0 {
-		return "", "", errors.New("rules is empty")
-	}
-	expectedCluster := rules[0].Cluster
-	expectedBackend := rules[0].Backend
-	if expectedBackend == "" {
-		expectedBackend = "triton"
+		return errors.New("rules is empty")
 	}
 
 	for i := range rules {
-		if rules[i].Cluster != expectedCluster {
-			return "", "", fmt.Errorf("mismatched cluster, current=%s, expected=%s", rules[i].Cluster, expectedCluster)
-		}
 		if rules[i].Backend == "" {
 			rules[i].Backend = "triton"
 		}
-		if rules[i].Backend != expectedBackend {
-			return "", "", fmt.Errorf("mismatched backend, current=%s, expected=%s", rules[i].Backend, expectedBackend)
+		if err := rules[i].validateSplit(); err != nil {
+			return fmt.Errorf("rule %s: %v", rules[i].SceneName, err)
+		}
+		if err := rules[i].compileMatchers(); err != nil {
+			return fmt.Errorf("rule %s: %v", rules[i].SceneName, err)
+		}
+	}
+
+	// 没有匹配条件的规则会匹配所有请求，排在其后的有条件规则永远不会被匹配
+	// 只有显式配置 order 时才可能出现，未配置 order 时默认规则总是排在最后
+	tuples := make([]Tuple, 0, len(rules))
+	for _, rule := range rules {
+		tuples = append(tuples, Tuple{TargetModel: rule})
+	}
+	SortTuples(tuples)
+	conditional := false
+	for i := len(tuples) - 1; i >= 0; i-- {
+		if tuples[i].TargetModel.conditionCount() > 0 {
+			conditional = true
+		} else if conditional {
+			return fmt.Errorf("rule %s has no match conditions but rules with conditions follow it, set order to match it last",
+				tuples[i].TargetModel.SceneName)
 		}
 	}
 
-	return expectedCluster, expectedBackend, nil
+	return nil
 }
 
 // Parse 解析并验证配置
@@ -268,10 +535,145 @@ func (c *LLMProxyConfig) Parse() error {
 	mappingRules := c.GetModelMappingRule()
 	if len(mappingRules) > 0 {
 		for key, rule := range mappingRules {
-			_, _, err := validateRules(rule.Rules)
-			if err != nil {
+			if err := validateRules(rule.Rules); err != nil {
 				return fmt.Errorf("rules validation error, model=%s, err=%v", key, err)
 			}
+			if isModelPattern(key) {
+				if _, err := compileModelPattern(key); err != nil {
+					return fmt.Errorf("rules validation error, model=%s, err=%v", key, err)
+				}
+			}
+			if err := c.validateFallbacks(key, rule.Fallbacks); err != nil {
+				return fmt.Errorf("fallbacks validation error, model=%s, err=%v", key, err)
+			}
+			if rule.Mirror != nil {
+				if err := rule.Mirror.validate(); err != nil {
+					return fmt.Errorf("mirror validation error, model=%s, err=%v", key, err)
+				}
+			}
+		}
+	}
+
+	for key, lbConfig := range c.GetLbMappingRule() {
+		if err := validateLbConfig(lbConfig); err != nil {
+			return fmt.Errorf("lb config validation error, model=%s, err=%v", key, err)
+		}
+	}
+
+	for key, rateLimit := range c.GetRateLimitRule() {
+		if err := validateRateLimitConfig(rateLimit); err != nil {
+			return fmt.Errorf("rate limit config validation error, model=%s, err=%v", key, err)
+		}
+	}
+
+	for key, quota := range c.GetQuotaRule() {
+		if err := validateQuotaConfig(quota); err != nil {
+			return fmt.Errorf("quota config validation error, model=%s, err=%v", key, err)
+		}
+	}
+
+	if c.Auth != nil {
+		if err := c.Auth.validate(); err != nil {
+			return fmt.Errorf("auth config validation error: %v", err)
+		}
+		for _, key := range c.Auth.Keys {
+			for _, model := range key.Models {
+				if _, ok := mappingRules[model]; !ok && c.MatchModel(model) == nil && c.FindAutoModel(model) == nil {
+					return fmt.Errorf("auth config validation error, tenant=%s, err=model %s not found in model_mapping_rule", key.Tenant, model)
+				}
+			}
+		}
+	}
+
+	if c.Env != nil {
+		if err := c.Env.validate(); err != nil {
+			return fmt.Errorf("env config validation error: %v", err)
+		}
+	}
+	if err := c.validateEnvModels(); err != nil {
+		return fmt.Errorf("env model validation error, %v", err)
+	}
+
+	if err := c.validateChains(); err != nil {
+		return fmt.Errorf("chain validation error, %v", err)
+	}
+
+	if err := c.validateAutoModels(); err != nil {
+		return fmt.Errorf("auto model validation error, %v", err)
+	}
+
+	if c.JWT != nil {
+		if err := c.JWT.validate(); err != nil {
+			return fmt.Errorf("jwt config validation error: %v", err)
+		}
+	}
+	for key, rule := range mappingRules {
+		for _, r := range rule.GetRules() {
+			if len(r.Claims) > 0 && c.JWT == nil {
+				return fmt.Errorf("rules validation error, model=%s, err=jwt config is required for claims matching", key)
+			}
+		}
+	}
+
+	if c.Priority != nil {
+		if err := c.Priority.validate(); err != nil {
+			return fmt.Errorf("priority config validation error: %v", err)
+		}
+		for key, rule := range mappingRules {
+			for _, r := range rule.GetRules() {
+				if _, ok := c.Priority.Classes[r.Priority]; r.Priority != "" && !ok {
+					return fmt.Errorf("rules validation error, model=%s, err=unknown priority %s", key, r.Priority)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateLbConfig 验证负载均衡配置
+func validateLbConfig(lbConfig *LBConfig) error {
+	if lbConfig == nil {
+		return nil
+	}
+	switch lbConfig.SelectStrategy {
+	case "", types.SelectTopPercent, types.SelectScoreGap, types.SelectSoftmax:
+	default:
+		return fmt.Errorf("unknown select strategy %s", lbConfig.SelectStrategy)
+	}
+	if lbConfig.SoftmaxTemperature < 0 {
+		return fmt.Errorf("negative softmax temperature %.3f", lbConfig.SoftmaxTemperature)
+	}
+	if lbConfig.ScoreGap < 0 {
+		return fmt.Errorf("negative score gap %.3f", lbConfig.ScoreGap)
+	}
+	if lbConfig.LoraPollIntervalMs < 0 {
+		return fmt.Errorf("negative lora poll interval %d", lbConfig.LoraPollIntervalMs)
+	}
+	if slo := lbConfig.SLO; slo != nil {
+		if !lbConfig.LoadAwareEnable {
+			return errors.New("slo requires load_aware_enable")
+		}
+		switch slo.GetPolicy() {
+		case SLOPolicyNone, SLOPolicyReject:
+		case SLOPolicyOverflow:
+			if slo.OverflowCluster == "" {
+				return errors.New("overflow_cluster is required for slo policy overflow")
+			}
+		default:
+			return fmt.Errorf("unknown slo policy %s", slo.Policy)
+		}
+	}
+	if q := lbConfig.Queue; q != nil {
+		if q.HostQueueDepth <= 0 {
+			return errors.New("host_queue_depth must be positive when queue is enabled")
+		}
+		if q.MaxSize < 0 || q.MaxWaitMs < 0 || q.DispatchIntervalMs < 0 {
+			return errors.New("negative queue config")
+		}
+	}
+	if a := lbConfig.Affinity; a != nil {
+		if a.MaxQueueDepth < 0 || a.TTLSeconds < 0 {
+			return errors.New("negative affinity config")
 		}
 	}
 	return nil
@@ -289,31 +691,17 @@ func GetModelMappings(modelMappings map[string]*Mapping, modelName string) []Tup
 	return mapping.Tuples
 }
 
-// GetCandidateRule 根据请求头从候选规则中选择规则
-// 如果只有一个候选规则且没有 headers，直接返回
-// 否则通过匹配每个规则的 headers 来选择
-func GetCandidateRule(targetModelTuple []Tuple, headers api.RequestHeaderMap) *Rule {
-	if len(targetModelTuple) == 1 && len(targetModelTuple[0].TargetModel.Headers) == 0 {
+// GetCandidateRule 根据请求从候选规则中选择规则
+// 如果只有一个候选规则且没有匹配条件，直接返回
+// 否则按顺序返回第一个匹配条件全部满足的规则
+func GetCandidateRule(targetModelTuple []Tuple, in *MatchInput) *Rule {
+	if len(targetModelTuple) == 1 && targetModelTuple[0].TargetModel.conditionCount() == 0 {
 		return targetModelTuple[0].TargetModel
 	}
 
 	for _, tuple := range targetModelTuple {
-		model := tuple.TargetModel
-		if len(model.Headers) == 0 {
-			// headers 为空，是默认路由，直接返回
-			return model
-		}
-
-		allMatched := true
-		for _, v := range model.Headers {
-			if reqHeaderValue, ok := headers.Get(v.Key); !ok || reqHeaderValue != v.Value {
-				allMatched = false
-				break
-			}
-		}
-
-		if allMatched {
-			return model
+		if tuple.TargetModel.matches(in) {
+			return tuple.TargetModel
 		}
 	}
 
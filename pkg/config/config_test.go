@@ -0,0 +1,71 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestValidateRulesDefaultRule(t *testing.T) {
+	// conditional 创建匹配请求头 x-env 的规则
+	conditional := func(name string, order int32) *Rule {
+		return &Rule{SceneName: name, Order: order, Headers: []*ValueMatcher{{Key: "x-env", Value: name}}}
+	}
+	unconditional := func(name string, order int32) *Rule {
+		return &Rule{SceneName: name, Order: order}
+	}
+
+	tests := []struct {
+		name    string
+		rules   []*Rule
+		wantErr bool
+	}{
+		{
+			name:  "single default rule",
+			rules: []*Rule{unconditional("default", 0)},
+		},
+		{
+			name:  "default rule sorted last without order",
+			rules: []*Rule{unconditional("default", 0), conditional("prod", 0)},
+		},
+		{
+			name:  "default rule with explicit last order",
+			rules: []*Rule{conditional("prod", 1), unconditional("default", 2)},
+		},
+		{
+			name:    "default rule ordered before conditional rule",
+			rules:   []*Rule{unconditional("default", 1), conditional("prod", 2)},
+			wantErr: true,
+		},
+		{
+			name:    "conditional rule between two default rules",
+			rules:   []*Rule{unconditional("first", 1), conditional("prod", 2), unconditional("last", 3)},
+			wantErr: true,
+		},
+		{
+			name:  "several default rules after all conditional rules",
+			rules: []*Rule{conditional("prod", 1), unconditional("a", 2), unconditional("b", 3)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRules(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
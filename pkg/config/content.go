@@ -0,0 +1,145 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// RequestAttributes 转码器解析出的请求属性，用于请求内容匹配
+type RequestAttributes struct {
+	// HasImage 请求包含图片
+	HasImage bool
+	// PromptLength Prompt 文本长度（字节）
+	PromptLength int
+	// Stream 是否流式请求
+	Stream bool
+	// MaxTokens 请求的最大输出 Token 数，未设置时为 0
+	MaxTokens int
+	// HasTools 请求携带了 tools 或 functions
+	HasTools bool
+	// ResponseFormat response_format 的类型，未设置时为空
+	ResponseFormat string
+}
+
+// Range 数值范围，Min、Max 为 0 表示不限制
+type Range struct {
+	Min int64 `json:"min,omitempty"`
+	Max int64 `json:"max,omitempty"`
+}
+
+// Contains 判断值是否在范围内 [Min, Max]
+func (r *Range) Contains(v int64) bool {
+	if r.Min > 0 && v < r.Min {
+		return false
+	}
+	if r.Max > 0 && v > r.Max {
+		return false
+	}
+	return true
+}
+
+func (r *Range) validate() error {
+	if r.Min < 0 || r.Max < 0 {
+		return errors.New("negative range")
+	}
+	if r.Max > 0 && r.Min > r.Max {
+		return fmt.Errorf("min %d greater than max %d", r.Min, r.Max)
+	}
+	return nil
+}
+
+// ContentMatch 请求内容匹配条件，所有配置的条件都满足时匹配
+type ContentMatch struct {
+	// HasImage 是否包含图片
+	HasImage *bool `json:"has_image,omitempty"`
+	// PromptLength Prompt 文本长度（字节）范围
+	PromptLength *Range `json:"prompt_length,omitempty"`
+	// Stream 是否流式请求
+	Stream *bool `json:"stream,omitempty"`
+	// MaxTokens max_tokens 范围，请求未设置 max_tokens 时按 0 处理
+	MaxTokens *Range `json:"max_tokens,omitempty"`
+	// HasTools 是否携带 tools 或 functions
+	HasTools *bool `json:"has_tools,omitempty"`
+	// ResponseFormat response_format 的类型，如 json_object、json_schema，text 匹配未设置的请求
+	ResponseFormat []string `json:"response_format,omitempty"`
+}
+
+// conditionCount 匹配条件数量
+func (c *ContentMatch) conditionCount() int {
+	if c == nil {
+		return 0
+	}
+	n := 0
+	for _, set := range []bool{c.HasImage != nil, c.PromptLength != nil, c.Stream != nil,
+		c.MaxTokens != nil, c.HasTools != nil, len(c.ResponseFormat) > 0} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// Match 判断请求属性是否满足所有匹配条件
+func (c *ContentMatch) Match(attrs *RequestAttributes) bool {
+	if c == nil {
+		return true
+	}
+	if attrs == nil {
+		return c.conditionCount() == 0
+	}
+	if c.HasImage != nil && *c.HasImage != attrs.HasImage {
+		return false
+	}
+	if c.PromptLength != nil && !c.PromptLength.Contains(int64(attrs.PromptLength)) {
+		return false
+	}
+	if c.Stream != nil && *c.Stream != attrs.Stream {
+		return false
+	}
+	if c.MaxTokens != nil && !c.MaxTokens.Contains(int64(attrs.MaxTokens)) {
+		return false
+	}
+	if c.HasTools != nil && *c.HasTools != attrs.HasTools {
+		return false
+	}
+	if len(c.ResponseFormat) > 0 {
+		format := attrs.ResponseFormat
+		if format == "" {
+			format = "text"
+		}
+		if !slices.Contains(c.ResponseFormat, format) {
+			return false
+		}
+	}
+	return true
+}
+
+// validate 验证请求内容匹配条件
+func (c *ContentMatch) validate() error {
+	if c.PromptLength != nil {
+		if err := c.PromptLength.validate(); err != nil {
+			return fmt.Errorf("prompt_length: %v", err)
+		}
+	}
+	if c.MaxTokens != nil {
+		if err := c.MaxTokens.validate(); err != nil {
+			return fmt.Errorf("max_tokens: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+const (
+	// DefaultEnvHeader 默认的环境标识请求头
+	DefaultEnvHeader = "x-llm-env"
+
+	// EnvSourceAPIKey 从 API Key 的 env 字段解析环境标识
+	EnvSourceAPIKey = "api_key"
+	// EnvSourceHeader 从客户端传入的环境标识请求头解析
+	EnvSourceHeader = "header"
+	// EnvSourceHost 按 :authority 匹配 hosts 解析
+	EnvSourceHost = "host"
+)
+
+// defaultEnvSources 默认的环境标识来源及顺序
+var defaultEnvSources = []string{EnvSourceAPIKey, EnvSourceHeader, EnvSourceHost}
+
+// EnvConfig 环境标识配置
+// 按 sources 的顺序解析环境标识，解析结果写入 header 请求头，用于匹配 env_model_mapping_rule
+type EnvConfig struct {
+	// Header 环境标识请求头，解析结果会覆盖客户端传入的值
+	Header string `json:"header,omitempty"`
+	// Sources 环境标识来源及顺序（api_key、header、host）
+	Sources []string `json:"sources,omitempty"`
+	// Hosts 按 :authority 匹配环境标识，按顺序匹配第一个
+	Hosts []*HostEnv `json:"hosts,omitempty"`
+	// Default 无法解析时使用的环境标识
+	Default string `json:"default,omitempty"`
+}
+
+// HostEnv :authority 到环境标识的映射
+type HostEnv struct {
+	// Host 主机名，支持 * 和 ? 通配，以 ~ 开头为正则，不区分大小写，不含端口
+	Host string `json:"host"`
+	// Env 环境标识
+	Env string `json:"env"`
+
+	pattern *modelPattern
+}
+
+// GetHeader 获取环境标识请求头
+func (e *EnvConfig) GetHeader() string {
+	if e == nil || e.Header == "" {
+		return DefaultEnvHeader
+	}
+	return e.Header
+}
+
+// GetSources 获取环境标识来源
+func (e *EnvConfig) GetSources() []string {
+	if len(e.Sources) == 0 {
+		return defaultEnvSources
+	}
+	return e.Sources
+}
+
+// Resolve 按来源顺序解析环境标识
+func (e *EnvConfig) Resolve(headers api.RequestHeaderMap, key *APIKeyConfig) string {
+	for _, source := range e.GetSources() {
+		var env string
+		switch source {
+		case EnvSourceAPIKey:
+			if key != nil {
+				env = key.Env
+			}
+		case EnvSourceHeader:
+			env, _ = headers.Get(e.GetHeader())
+		case EnvSourceHost:
+			env = e.matchHost(headers.Host())
+		}
+		if env = strings.TrimSpace(env); env != "" {
+			return env
+		}
+	}
+	return e.Default
+}
+
+// matchHost 按 :authority 匹配环境标识
+func (e *EnvConfig) matchHost(authority string) string {
+	if authority == "" {
+		return ""
+	}
+	host := authority
+	if h, _, err := net.SplitHostPort(authority); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, h := range e.Hosts {
+		if h.pattern != nil && h.pattern.re.MatchString(host) {
+			return h.Env
+		}
+	}
+	return ""
+}
+
+// validate 验证环境标识配置并编译主机名规则
+func (e *EnvConfig) validate() error {
+	for _, source := range e.Sources {
+		switch source {
+		case EnvSourceAPIKey, EnvSourceHeader, EnvSourceHost:
+		default:
+			return fmt.Errorf("unknown env source %s", source)
+		}
+	}
+	for _, h := range e.Hosts {
+		if h.Host == "" || h.Env == "" {
+			return errors.New("host and env are required")
+		}
+		key := h.Host
+		if !strings.HasPrefix(key, modelRegexPrefix) {
+			key = strings.ToLower(key)
+		}
+		p, err := compileModelPattern(key)
+		if err != nil {
+			return fmt.Errorf("invalid host %s: %v", h.Host, err)
+		}
+		h.pattern = p
+	}
+	return nil
+}
+
+// MatchModelInEnv 查找模型名在环境中对应的规则
+// 按 model_mapping_rule 匹配模型名，env_model_mapping_rule 中该环境配置了同一规则键时使用环境的规则
+func (c *LLMProxyConfig) MatchModelInEnv(env, modelName string) *ModelMatch {
+	match := c.MatchModel(modelName)
+	if match == nil || env == "" {
+		return match
+	}
+	if tuples := GetModelMappings(c.envModelMappings[env], match.Key); len(tuples) > 0 {
+		match.Tuples = tuples
+	}
+	return match
+}
+
+// buildEnvModelMappings 构建各环境的模型映射
+func buildEnvModelMappings(envRules map[string]map[string]*Rules) map[string]map[string]*Mapping {
+	mappings := make(map[string]map[string]*Mapping, len(envRules))
+	for env, mappingRules := range envRules {
+		mappings[env] = buildModelMappings(mappingRules)
+	}
+	return mappings
+}
+
+// validateEnvModels 验证各环境的模型映射
+// 环境规则只覆盖 model_mapping_rule 中已有规则键的 rules，降级和镜像使用 model_mapping_rule 的配置
+func (c *LLMProxyConfig) validateEnvModels() error {
+	if len(c.EnvModelMappingRule) > 0 && c.Env == nil {
+		return errors.New("env config is required for env_model_mapping_rule")
+	}
+	for env, mappingRules := range c.EnvModelMappingRule {
+		for key, rule := range mappingRules {
+			if _, ok := c.GetModelMappingRule()[key]; !ok {
+				return fmt.Errorf("env=%s, model=%s, err=model not found in model_mapping_rule", env, key)
+			}
+			if len(rule.Fallbacks) > 0 || rule.Mirror != nil {
+				return fmt.Errorf("env=%s, model=%s, err=fallbacks and mirror must be configured in model_mapping_rule", env, key)
+			}
+			if err := validateRules(rule.Rules); err != nil {
+				return fmt.Errorf("env=%s, model=%s, err=%v", env, key, err)
+			}
+			for _, r := range rule.Rules {
+				if len(r.Claims) > 0 && c.JWT == nil {
+					return fmt.Errorf("env=%s, model=%s, err=jwt config is required for claims matching", env, key)
+				}
+				if c.Priority == nil || r.Priority == "" {
+					continue
+				}
+				if _, ok := c.Priority.Classes[r.Priority]; !ok {
+					return fmt.Errorf("env=%s, model=%s, err=unknown priority %s", env, key, r.Priority)
+				}
+			}
+		}
+	}
+	return nil
+}
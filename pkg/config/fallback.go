@@ -0,0 +1,81 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Fallback 降级目标
+// 当前集群没有可用主机或所有主机饱和时，按配置顺序尝试降级目标
+type Fallback struct {
+	// Model 降级到的模型，重新匹配 model_mapping_rule
+	Model string `json:"model,omitempty"`
+	// Cluster 降级到的集群，模型不变
+	Cluster string `json:"cluster,omitempty"`
+	// Backend 降级集群的后端协议类型，为空时使用原规则的后端
+	Backend string `json:"backend,omitempty"`
+}
+
+// Target 降级目标的名称，用于日志和响应头
+func (f *Fallback) Target() string {
+	if f.Model != "" {
+		return f.Model
+	}
+	return f.Cluster
+}
+
+// validate 验证降级目标
+func (f *Fallback) validate() error {
+	if (f.Model == "") == (f.Cluster == "") {
+		return errors.New("exactly one of model and cluster is required")
+	}
+	if f.Model != "" && f.Backend != "" {
+		return fmt.Errorf("fallback model %s: backend is only allowed for cluster", f.Model)
+	}
+	return nil
+}
+
+// FindFallbacks 查找模型的降级目标
+func (c *LLMProxyConfig) FindFallbacks(modelKey string) []*Fallback {
+	if c.ModelMappings == nil || modelKey == "" {
+		return nil
+	}
+	mapping, ok := c.ModelMappings[modelKey]
+	if !ok {
+		return nil
+	}
+	return mapping.Fallbacks
+}
+
+// validateFallbacks 验证模型的降级目标，降级模型必须能匹配 model_mapping_rule
+func (c *LLMProxyConfig) validateFallbacks(model string, fallbacks []*Fallback) error {
+	for _, fb := range fallbacks {
+		if err := fb.validate(); err != nil {
+			return err
+		}
+		if fb.Model == "" {
+			continue
+		}
+		if fb.Model == model {
+			return fmt.Errorf("fallback model %s is the model itself", fb.Model)
+		}
+		if c.MatchModel(fb.Model) == nil {
+			return fmt.Errorf("fallback model %s not found in model_mapping_rule", fb.Model)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultJWTMetadataFilter Envoy jwt_authn 过滤器写入 Dynamic Metadata 的命名空间
+const DefaultJWTMetadataFilter = "envoy.filters.http.jwt_authn"
+
+// JWTConfig JWT Claims 配置
+// JWT 由 Istio RequestAuthentication（Envoy jwt_authn 过滤器）验证，本过滤器只读取验证后的 Claims
+type JWTConfig struct {
+	// MetadataFilter Claims 所在的 Dynamic Metadata 命名空间，默认 envoy.filters.http.jwt_authn
+	MetadataFilter string `json:"metadata_filter,omitempty"`
+	// MetadataKey Claims 在 Dynamic Metadata 中的键（jwt_authn 的 payload_in_metadata 或 issuer）
+	MetadataKey string `json:"metadata_key,omitempty"`
+	// PayloadHeader Base64URL 编码的 JWT Payload 请求头（RequestAuthentication 的 outputPayloadToHeader）
+	// 仅在 MetadataKey 未配置或 Dynamic Metadata 中没有 Claims 时使用；MetadataFilter 命名空间为空时视为伪造并删除
+	PayloadHeader string `json:"payload_header,omitempty"`
+	// Required 是否要求请求必须携带 Claims，缺少时返回 401
+	Required bool `json:"required,omitempty"`
+	// TenantClaim 作为租户标识的 Claim，API Key 认证未设置租户时使用
+	TenantClaim string `json:"tenant_claim,omitempty"`
+	// TenantHeader 写入租户标识的请求头，默认 x-tenant-id
+	TenantHeader string `json:"tenant_header,omitempty"`
+	// ModelsClaim 允许访问的模型列表所在的 Claim，配置后不在列表中的模型返回 403
+	ModelsClaim string `json:"models_claim,omitempty"`
+}
+
+// GetMetadataFilter 获取 Claims 所在的 Dynamic Metadata 命名空间
+func (j *JWTConfig) GetMetadataFilter() string {
+	if j.MetadataFilter == "" {
+		return DefaultJWTMetadataFilter
+	}
+	return j.MetadataFilter
+}
+
+// GetTenantHeader 获取写入租户标识的请求头
+func (j *JWTConfig) GetTenantHeader() string {
+	if j.TenantHeader == "" {
+		return DefaultTenantHeader
+	}
+	return j.TenantHeader
+}
+
+// AllowModel 判断 Claims 是否允许访问模型
+// 未配置 ModelsClaim 时不限制；配置后 Claim 缺失视为不允许
+func (j *JWTConfig) AllowModel(claims Claims, model string) bool {
+	if j.ModelsClaim == "" {
+		return true
+	}
+	return claims.Match(j.ModelsClaim, model)
+}
+
+// validate 验证 JWT 配置
+func (j *JWTConfig) validate() error {
+	if j.MetadataKey == "" && j.PayloadHeader == "" {
+		return errors.New("metadata_key or payload_header is required")
+	}
+	return nil
+}
+
+// ClaimValue Claim 匹配条件
+type ClaimValue struct {
+	// Name Claim 名称，嵌套 Claim 使用点号分隔，如 realm_access.roles
+	Name string `json:"name"`
+	// Value 期望值，数组 Claim 包含该值即匹配
+	Value string `json:"value"`
+}
+
+// Claims 已验证的 JWT Claims
+type Claims map[string]any
+
+// Lookup 按点号分隔的路径查找 Claim
+func (c Claims) Lookup(path string) (any, bool) {
+	var cur any = map[string]any(c)
+	for _, name := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Match 判断 Claim 是否等于期望值
+// 数组 Claim 包含期望值即匹配；字符串 Claim 也可以是空格分隔的列表（如 OAuth2 scope）
+func (c Claims) Match(path, value string) bool {
+	claim, ok := c.Lookup(path)
+	if !ok {
+		return false
+	}
+	switch v := claim.(type) {
+	case string:
+		return v == value || slices.Contains(strings.Fields(v), value)
+	case []any:
+		for _, item := range v {
+			if fmt.Sprint(item) == value {
+				return true
+			}
+		}
+		return false
+	default:
+		return fmt.Sprint(v) == value
+	}
+}
+
+// String 获取字符串类型的 Claim
+func (c Claims) String(path string) string {
+	claim, ok := c.Lookup(path)
+	if !ok {
+		return ""
+	}
+	if s, ok := claim.(string); ok {
+		return s
+	}
+	return fmt.Sprint(claim)
+}
@@ -0,0 +1,174 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+// ValueMatcher 请求头或查询参数匹配条件
+// Value、Prefix、Suffix、Regex、Present 最多配置一个，都不配置时精确匹配空值（兼容 {key, value: ""} 写法）
+type ValueMatcher struct {
+	// Key 请求头或查询参数名称
+	Key string `json:"key"`
+	// Value 精确匹配
+	Value string `json:"value,omitempty"`
+	// Prefix 前缀匹配
+	Prefix string `json:"prefix,omitempty"`
+	// Suffix 后缀匹配
+	Suffix string `json:"suffix,omitempty"`
+	// Regex 正则匹配（RE2 语法，需要匹配完整的值）
+	Regex string `json:"regex,omitempty"`
+	// Present true 表示存在即匹配，false 表示不存在时匹配
+	Present *bool `json:"present,omitempty"`
+	// Invert 对匹配结果取反
+	Invert bool `json:"invert,omitempty"`
+
+	// regex 编译后的正则，Parse 时生成
+	regex *regexp.Regexp
+}
+
+// compile 验证匹配条件并编译正则
+func (m *ValueMatcher) compile() error {
+	if m.Key == "" {
+		return errors.New("key is required")
+	}
+
+	n := 0
+	for _, set := range []bool{m.Value != "", m.Prefix != "", m.Suffix != "", m.Regex != "", m.Present != nil} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("key %s: at most one of value, prefix, suffix, regex, present is allowed", m.Key)
+	}
+
+	if m.Regex != "" {
+		re, err := regexp.Compile("^(?:" + m.Regex + ")$")
+		if err != nil {
+			return fmt.Errorf("key %s: invalid regex: %v", m.Key, err)
+		}
+		m.regex = re
+	}
+	return nil
+}
+
+// Match 判断值是否满足匹配条件，ok 表示请求中是否存在该键
+func (m *ValueMatcher) Match(value string, ok bool) bool {
+	var matched bool
+	switch {
+	case m.Present != nil:
+		matched = ok == *m.Present
+	case !ok:
+		matched = false
+	case m.Prefix != "":
+		matched = strings.HasPrefix(value, m.Prefix)
+	case m.Suffix != "":
+		matched = strings.HasSuffix(value, m.Suffix)
+	case m.Regex != "":
+		matched = m.regex != nil && m.regex.MatchString(value)
+	default:
+		matched = value == m.Value
+	}
+	return matched != m.Invert
+}
+
+// MatchInput 规则匹配的请求信息
+type MatchInput struct {
+	// Headers 请求头
+	Headers api.RequestHeaderMap
+	// Claims 已验证的 JWT Claims，未配置 jwt 时为 nil
+	Claims Claims
+	// Request 解析后的请求属性，用于请求内容匹配
+	Request *RequestAttributes
+
+	// query 查询参数，首次使用时解析
+	query url.Values
+}
+
+// getQuery 获取查询参数
+func (in *MatchInput) getQuery() url.Values {
+	if in.query == nil {
+		in.query = url.Values{}
+		if _, rawQuery, ok := strings.Cut(in.Headers.Path(), "?"); ok {
+			if values, err := url.ParseQuery(rawQuery); err == nil {
+				in.query = values
+			}
+		}
+	}
+	return in.query
+}
+
+// compileMatchers 验证规则的匹配条件
+func (r *Rule) compileMatchers() error {
+	for _, m := range r.Headers {
+		if err := m.compile(); err != nil {
+			return fmt.Errorf("invalid header matcher, %v", err)
+		}
+	}
+	for _, m := range r.QueryParams {
+		if err := m.compile(); err != nil {
+			return fmt.Errorf("invalid query param matcher, %v", err)
+		}
+	}
+	for _, c := range r.Claims {
+		if c.Name == "" {
+			return errors.New("invalid claim matcher, name is required")
+		}
+	}
+	if r.Content != nil {
+		if err := r.Content.validate(); err != nil {
+			return fmt.Errorf("invalid content matcher, %v", err)
+		}
+	}
+	return nil
+}
+
+// conditionCount 规则的匹配条件数量
+func (r *Rule) conditionCount() int {
+	return len(r.Headers) + len(r.QueryParams) + len(r.Claims) + r.Content.conditionCount()
+}
+
+// matches 判断请求是否满足规则的所有匹配条件
+func (r *Rule) matches(in *MatchInput) bool {
+	for _, m := range r.Headers {
+		value, ok := in.Headers.Get(m.Key)
+		if !m.Match(value, ok) {
+			return false
+		}
+	}
+	if len(r.QueryParams) > 0 {
+		query := in.getQuery()
+		for _, m := range r.QueryParams {
+			_, ok := query[m.Key]
+			if !m.Match(query.Get(m.Key), ok) {
+				return false
+			}
+		}
+	}
+	for _, c := range r.Claims {
+		if !in.Claims.Match(c.Name, c.Value) {
+			return false
+		}
+	}
+	return r.Content.Match(in.Request)
+}
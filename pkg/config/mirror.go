@@ -0,0 +1,73 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// MirrorConfig 请求镜像配置
+// 按比例采样请求，异步复制到影子集群，影子响应不返回给客户端
+type MirrorConfig struct {
+	// Cluster 影子集群
+	Cluster string `json:"cluster"`
+	// Model 影子请求使用的模型名，为空时使用原请求转发的模型名
+	Model string `json:"model,omitempty"`
+	// Percent 采样百分比 (0, 100]
+	Percent float64 `json:"percent"`
+	// TimeoutMs 影子请求超时时间（毫秒），默认 60s
+	TimeoutMs int32 `json:"timeout_ms,omitempty"`
+}
+
+// Sample 按采样百分比判断是否镜像本次请求
+func (m *MirrorConfig) Sample() bool {
+	if m == nil {
+		return false
+	}
+	return rand.Float64()*100 < m.Percent
+}
+
+// GetTimeout 获取影子请求超时时间，未配置时返回 0 使用默认值
+func (m *MirrorConfig) GetTimeout() time.Duration {
+	return time.Duration(m.TimeoutMs) * time.Millisecond
+}
+
+// validate 验证镜像配置
+func (m *MirrorConfig) validate() error {
+	if m.Cluster == "" {
+		return errors.New("cluster is required")
+	}
+	if m.Percent <= 0 || m.Percent > 100 {
+		return errors.New("percent must be in (0, 100]")
+	}
+	if m.TimeoutMs < 0 {
+		return errors.New("timeout_ms must be non-negative")
+	}
+	return nil
+}
+
+// FindMirror 查找模型的镜像配置
+func (c *LLMProxyConfig) FindMirror(modelKey string) *MirrorConfig {
+	if c.ModelMappings == nil || modelKey == "" {
+		return nil
+	}
+	mapping, ok := c.ModelMappings[modelKey]
+	if !ok {
+		return nil
+	}
+	return mapping.Mirror
+}
@@ -0,0 +1,159 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// CatchAllModel 匹配所有模型名的兜底规则键
+	CatchAllModel = "*"
+	// modelRegexPrefix 正则模型名规则键前缀
+	modelRegexPrefix = "~"
+)
+
+// modelPattern 通配或正则模型名规则
+type modelPattern struct {
+	key string
+	re  *regexp.Regexp
+}
+
+// isModelPattern 判断 model_mapping_rule 键是否为通配或正则模型名
+func isModelPattern(key string) bool {
+	return strings.HasPrefix(key, modelRegexPrefix) || strings.ContainsAny(key, "*?")
+}
+
+// compileModelPattern 编译模型名规则
+// 正则规则以 ~ 开头；通配规则中 * 匹配任意字符串，? 匹配单个字符，每个通配符都是一个捕获组
+func compileModelPattern(key string) (*modelPattern, error) {
+	var expr string
+	if strings.HasPrefix(key, modelRegexPrefix) {
+		expr = "^(?:" + strings.TrimPrefix(key, modelRegexPrefix) + ")$"
+	} else {
+		var sb strings.Builder
+		sb.WriteString("^")
+		for _, r := range key {
+			switch r {
+			case '*':
+				sb.WriteString("(.*)")
+			case '?':
+				sb.WriteString("(.)")
+			default:
+				sb.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		sb.WriteString("$")
+		expr = sb.String()
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model pattern %s: %v", key, err)
+	}
+	return &modelPattern{key: key, re: re}, nil
+}
+
+// buildModelPatterns 编译所有通配和正则模型名规则
+// 按规则键长度降序匹配，更具体的规则优先；兜底规则 * 最后匹配
+func buildModelPatterns(mappingRules map[string]*Rules) ([]*modelPattern, error) {
+	patterns := make([]*modelPattern, 0)
+	for key := range mappingRules {
+		if !isModelPattern(key) {
+			continue
+		}
+		p, err := compileModelPattern(key)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		a, b := patterns[i].key, patterns[j].key
+		if (a == CatchAllModel) != (b == CatchAllModel) {
+			return b == CatchAllModel
+		}
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return patterns, nil
+}
+
+// ModelMatch 模型名匹配结果
+type ModelMatch struct {
+	// Key 匹配的 model_mapping_rule 键，精确匹配时与模型名相同
+	Key string
+	// Tuples 候选规则
+	Tuples []Tuple
+
+	model   string
+	pattern *modelPattern
+	match   []int
+}
+
+// MatchModel 查找模型名对应的规则
+// 精确匹配优先，其次依次匹配通配和正则规则
+func (c *LLMProxyConfig) MatchModel(modelName string) *ModelMatch {
+	if tuples := GetModelMappings(c.ModelMappings, modelName); len(tuples) > 0 {
+		return &ModelMatch{Key: modelName, Tuples: tuples, model: modelName}
+	}
+
+	for _, p := range c.modelPatterns {
+		match := p.re.FindStringSubmatchIndex(modelName)
+		if match == nil {
+			continue
+		}
+		tuples := GetModelMappings(c.ModelMappings, p.key)
+		if len(tuples) == 0 {
+			continue
+		}
+		return &ModelMatch{Key: p.key, Tuples: tuples, model: modelName, pattern: p, match: match}
+	}
+	return nil
+}
+
+// Expand 展开模板中的捕获组引用，如 $1、${name}
+func (m *ModelMatch) Expand(template string) string {
+	if m.pattern == nil || !strings.Contains(template, "$") {
+		return template
+	}
+	return string(m.pattern.re.ExpandString(nil, template, m.model, m.match))
+}
+
+// ExpandRule 展开规则中 scene_name、lora、lora_path 的捕获组引用
+// 返回规则的副本，不修改共享的配置
+func (m *ModelMatch) ExpandRule(rule *Rule) *Rule {
+	if m.pattern == nil || rule == nil {
+		return rule
+	}
+
+	expanded := *rule
+	expanded.SceneName = m.Expand(rule.SceneName)
+	if len(rule.Subset) > 0 {
+		expanded.Subset = make([]*Subset, 0, len(rule.Subset))
+		for _, s := range rule.Subset {
+			subset := *s
+			subset.Lora = m.Expand(s.Lora)
+			subset.LoraPath = m.Expand(s.LoraPath)
+			expanded.Subset = append(expanded.Subset, &subset)
+		}
+	}
+	return &expanded
+}
@@ -0,0 +1,131 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestCompileModelPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		model    string
+		want     bool
+		template string
+		expanded string
+		wantErr  bool
+	}{
+		{name: "star matches any suffix", key: "qwen-*", model: "qwen-72b", want: true, template: "scene-$1", expanded: "scene-72b"},
+		{name: "star matches empty", key: "qwen-*", model: "qwen-", want: true, template: "[$1]", expanded: "[]"},
+		{name: "star requires literal prefix", key: "qwen-*", model: "llama-7b", want: false},
+		{name: "question mark matches one character", key: "qwen?-7b", model: "qwen2-7b", want: true, template: "v$1", expanded: "v2"},
+		{name: "question mark rejects two characters", key: "qwen?-7b", model: "qwen25-7b", want: false},
+		{name: "multiple wildcards capture in order", key: "*/lora-*", model: "qwen/lora-sql", want: true, template: "${2}@${1}", expanded: "sql@qwen"},
+		{name: "dot is literal in wildcard", key: "qwen2.5-*", model: "qwen2x5-7b", want: false},
+		{name: "wildcard match is anchored", key: "qwen-*", model: "my-qwen-7b", want: false},
+		{name: "regex with named group", key: "~deepseek-(?P<size>\\d+)b", model: "deepseek-67b", want: true, template: "ds-${size}", expanded: "ds-67"},
+		{name: "regex is anchored", key: "~deepseek-\\d+b", model: "deepseek-67b-chat", want: false},
+		{name: "regex alternation is anchored as a whole", key: "~a|b", model: "ab", want: false},
+		{name: "invalid regex", key: "~qwen-(", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compileModelPattern(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileModelPattern(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			c := &LLMProxyConfig{
+				ModelMappings: map[string]*Mapping{tt.key: {Tuples: []Tuple{{TargetModel: &Rule{}}}}},
+				modelPatterns: []*modelPattern{p},
+			}
+			match := c.MatchModel(tt.model)
+			if got := match != nil; got != tt.want {
+				t.Fatalf("MatchModel(%q) matched = %v, want %v", tt.model, got, tt.want)
+			}
+			if match == nil {
+				return
+			}
+			if match.Key != tt.key {
+				t.Errorf("MatchModel(%q).Key = %q, want %q", tt.model, match.Key, tt.key)
+			}
+			if got := match.Expand(tt.template); got != tt.expanded {
+				t.Errorf("Expand(%q) = %q, want %q", tt.template, got, tt.expanded)
+			}
+		})
+	}
+}
+
+func TestMatchModelPrecedence(t *testing.T) {
+	keys := []string{"qwen-72b", "qwen-*", "qwen-*-chat", "~qwen-\\d+b", CatchAllModel}
+	rules := make(map[string]*Rules, len(keys))
+	mappings := make(map[string]*Mapping, len(keys))
+	for _, key := range keys {
+		rules[key] = &Rules{}
+		mappings[key] = &Mapping{Tuples: []Tuple{{TargetModel: &Rule{}}}}
+	}
+	patterns, err := buildModelPatterns(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &LLMProxyConfig{ModelMappings: mappings, modelPatterns: patterns}
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{model: "qwen-72b", want: "qwen-72b"},
+		{model: "qwen-7b-chat", want: "qwen-*-chat"},
+		{model: "qwen-14b", want: "~qwen-\\d+b"},
+		{model: "qwen-max", want: "qwen-*"},
+		{model: "llama-8b", want: CatchAllModel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			match := c.MatchModel(tt.model)
+			if match == nil || match.Key != tt.want {
+				t.Errorf("MatchModel(%q) = %+v, want key %q", tt.model, match, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandRule(t *testing.T) {
+	p, err := compileModelPattern("*/lora-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := &Rule{
+		SceneName: "scene-$1",
+		Subset:    []*Subset{{Name: "s", Lora: "$2", LoraPath: "/adapters/$1/$2"}},
+	}
+	c := &LLMProxyConfig{
+		ModelMappings: map[string]*Mapping{p.key: {Tuples: []Tuple{{TargetModel: rule}}}},
+		modelPatterns: []*modelPattern{p},
+	}
+
+	got := c.MatchModel("qwen/lora-sql").ExpandRule(rule)
+	if got.SceneName != "scene-qwen" || got.Subset[0].Lora != "sql" || got.Subset[0].LoraPath != "/adapters/qwen/sql" {
+		t.Errorf("ExpandRule() = scene %q, lora %q, lora_path %q", got.SceneName, got.Subset[0].Lora, got.Subset[0].LoraPath)
+	}
+	if rule.SceneName != "scene-$1" || rule.Subset[0].Lora != "$2" {
+		t.Error("ExpandRule() modified the shared rule")
+	}
+}
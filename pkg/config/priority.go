@@ -0,0 +1,152 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+// DefaultPriorityHeader 默认的请求优先级请求头
+const DefaultPriorityHeader = "x-llm-priority"
+
+// PriorityConfig 请求优先级配置
+// 优先级按以下顺序确定：已认证 API Key 的优先级 > API Key 映射 > 请求头 > 模型路由规则 > 默认优先级
+type PriorityConfig struct {
+	// Header 优先级请求头，默认 x-llm-priority
+	Header string `json:"header,omitempty"`
+	// HeaderClasses 请求头可以选择的优先级，为空时请求头只能选择不高于默认优先级的等级
+	HeaderClasses []string `json:"header_classes,omitempty"`
+	// Default 默认优先级
+	Default string `json:"default"`
+	// APIKeys API Key 的 SHA-256 摘要（十六进制）到优先级的映射
+	APIKeys map[string]string `json:"api_keys,omitempty"`
+	// Classes 优先级定义
+	Classes map[string]*PriorityClass `json:"classes"`
+}
+
+// PriorityClass 优先级定义
+type PriorityClass struct {
+	// Level 优先级等级，越小越优先；排队时高等级请求先于低等级请求出队
+	Level int32 `json:"level"`
+	// MaxInflight 本网关实例上该优先级的最大并发请求数，超过后直接丢弃，0 表示不限制
+	MaxInflight int32 `json:"max_inflight,omitempty"`
+	// ShedQueueDepth 所有候选主机的未完成请求数都达到该值时丢弃该优先级的请求，0 表示不丢弃
+	ShedQueueDepth int32 `json:"shed_queue_depth,omitempty"`
+	// QueueWeight 同等级内的公平调度权重，默认 1
+	QueueWeight int32 `json:"queue_weight,omitempty"`
+	// MaxQueueSize 该优先级的最大排队请求数，0 表示只受队列总长度限制
+	MaxQueueSize int32 `json:"max_queue_size,omitempty"`
+	// MaxWaitMs 该优先级的最长排队时间（毫秒），0 表示使用队列配置
+	MaxWaitMs int32 `json:"max_wait_ms,omitempty"`
+	// Selector 该优先级可用的主机标签选择器
+	Selector map[string]string `json:"selector,omitempty"`
+}
+
+// GetHeader 获取优先级请求头
+func (p *PriorityConfig) GetHeader() string {
+	if p == nil || p.Header == "" {
+		return DefaultPriorityHeader
+	}
+	return p.Header
+}
+
+// Resolve 确定请求的优先级
+// key 为认证成功的 API Key，未配置 auth 时为 nil；rulePriority 为匹配的模型路由规则中配置的优先级
+func (p *PriorityConfig) Resolve(headers api.RequestHeaderMap, key *APIKeyConfig, rulePriority string) (string, *PriorityClass) {
+	if p == nil {
+		return "", nil
+	}
+
+	if key != nil {
+		if class, ok := p.Classes[key.Priority]; ok {
+			return key.Priority, class
+		}
+	}
+	if apiKey := GetAPIKey(headers); apiKey != "" && len(p.APIKeys) > 0 {
+		if name, ok := p.APIKeys[HashAPIKey(apiKey)]; ok {
+			return name, p.Classes[name]
+		}
+	}
+	if name, ok := headers.Get(p.GetHeader()); ok {
+		if class, ok := p.Classes[name]; ok && p.allowHeader(name, class) {
+			return name, class
+		}
+	}
+	if class, ok := p.Classes[rulePriority]; ok {
+		return rulePriority, class
+	}
+	return p.Default, p.Classes[p.Default]
+}
+
+// allowHeader 判断请求头是否可以选择该优先级
+// 未配置 header_classes 时只能选择不高于默认优先级的等级，客户端只能降低自己的优先级
+func (p *PriorityConfig) allowHeader(name string, class *PriorityClass) bool {
+	if len(p.HeaderClasses) > 0 {
+		return slices.Contains(p.HeaderClasses, name)
+	}
+	return class.Level >= p.Classes[p.Default].Level
+}
+
+// validate 验证优先级配置
+func (p *PriorityConfig) validate() error {
+	if len(p.Classes) == 0 {
+		return errors.New("priority classes is empty")
+	}
+	if _, ok := p.Classes[p.Default]; !ok {
+		return fmt.Errorf("default priority %s not found in classes", p.Default)
+	}
+	for _, name := range p.HeaderClasses {
+		if _, ok := p.Classes[name]; !ok {
+			return fmt.Errorf("header priority %s not found in classes", name)
+		}
+	}
+	for key, name := range p.APIKeys {
+		if _, ok := p.Classes[name]; !ok {
+			return fmt.Errorf("priority %s of api key %s not found in classes", name, key)
+		}
+	}
+	for name, class := range p.Classes {
+		if class == nil {
+			return fmt.Errorf("priority class %s is empty", name)
+		}
+		if class.Level < 0 || class.MaxInflight < 0 || class.ShedQueueDepth < 0 ||
+			class.QueueWeight < 0 || class.MaxQueueSize < 0 || class.MaxWaitMs < 0 {
+			return fmt.Errorf("negative config in priority class %s", name)
+		}
+	}
+	return nil
+}
+
+// GetAPIKey 从 Authorization 请求头中获取 API Key
+func GetAPIKey(headers api.RequestHeaderMap) string {
+	auth, ok := headers.Get("authorization")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+}
+
+// HashAPIKey 计算 API Key 的 SHA-256 摘要，配置中只保存摘要
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
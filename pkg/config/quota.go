@@ -0,0 +1,167 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 额度统计周期
+const (
+	// QuotaPeriodDaily 按自然日（UTC）统计
+	QuotaPeriodDaily = "daily"
+	// QuotaPeriodMonthly 按自然月（UTC）统计
+	QuotaPeriodMonthly = "monthly"
+)
+
+// 额度单位
+const (
+	// QuotaUnitTokens 按 Token 数（输入 + 输出）计量
+	QuotaUnitTokens = "tokens"
+	// QuotaUnitCost 按费用计量，费用 = 输入 Token 数 × 输入单价 + 输出 Token 数 × 输出单价
+	QuotaUnitCost = "cost"
+)
+
+// 额度存储不可用时的处理方式
+const (
+	// QuotaFailureAllow 放行请求
+	QuotaFailureAllow = "allow"
+	// QuotaFailureDeny 拒绝请求
+	QuotaFailureDeny = "deny"
+)
+
+// DefaultQuotaWarnPercent 默认的额度预警百分比
+const DefaultQuotaWarnPercent = 80
+
+// QuotaConfig 模型的租户额度配置
+type QuotaConfig struct {
+	TenantKey
+	// Store 额度存储 (file, shared)，默认 file
+	Store string `json:"store,omitempty"`
+	// Period 统计周期 (daily, monthly)，默认 daily
+	Period string `json:"period,omitempty"`
+	// Unit 额度单位 (tokens, cost)，默认 tokens
+	Unit string `json:"unit,omitempty"`
+	// Limit 每个租户在统计周期内的额度，0 表示不限制
+	Limit float64 `json:"limit,omitempty"`
+	// InputPrice 每百万输入 Token 的单价，Unit 为 cost 时使用
+	InputPrice float64 `json:"input_price,omitempty"`
+	// OutputPrice 每百万输出 Token 的单价，Unit 为 cost 时使用
+	OutputPrice float64 `json:"output_price,omitempty"`
+	// WarnPercent 已用额度达到该百分比时在响应头中预警，默认 80
+	WarnPercent int32 `json:"warn_percent,omitempty"`
+	// Overrides 按租户标识覆盖的额度
+	Overrides map[string]float64 `json:"overrides,omitempty"`
+	// FailureMode 额度存储不可用时的处理方式 (allow, deny)，默认 allow
+	FailureMode string `json:"failure_mode,omitempty"`
+}
+
+// GetFailureMode 获取额度存储不可用时的处理方式
+func (q *QuotaConfig) GetFailureMode() string {
+	if q.FailureMode == "" {
+		return QuotaFailureAllow
+	}
+	return q.FailureMode
+}
+
+// GetPeriod 获取统计周期
+func (q *QuotaConfig) GetPeriod() string {
+	if q.Period == "" {
+		return QuotaPeriodDaily
+	}
+	return q.Period
+}
+
+// GetUnit 获取额度单位
+func (q *QuotaConfig) GetUnit() string {
+	if q.Unit == "" {
+		return QuotaUnitTokens
+	}
+	return q.Unit
+}
+
+// GetWarnPercent 获取额度预警百分比
+func (q *QuotaConfig) GetWarnPercent() int {
+	if q.WarnPercent <= 0 {
+		return DefaultQuotaWarnPercent
+	}
+	return int(q.WarnPercent)
+}
+
+// GetLimit 获取租户的额度
+func (q *QuotaConfig) GetLimit(key string) float64 {
+	if limit, ok := q.Overrides[key]; ok {
+		return limit
+	}
+	return q.Limit
+}
+
+// Usage 将 Token 用量换算为额度单位
+func (q *QuotaConfig) Usage(inputTokens, outputTokens int) float64 {
+	if q.GetUnit() == QuotaUnitCost {
+		return (float64(inputTokens)*q.InputPrice + float64(outputTokens)*q.OutputPrice) / 1e6
+	}
+	return float64(inputTokens + outputTokens)
+}
+
+// PeriodKey 获取当前统计周期的标识和过期时间
+// 过期时间多保留一个周期，便于跨周期结束的请求结算
+func (q *QuotaConfig) PeriodKey(now time.Time) (string, time.Duration) {
+	now = now.UTC()
+	if q.GetPeriod() == QuotaPeriodMonthly {
+		return now.Format("200601"), 62 * 24 * time.Hour
+	}
+	return now.Format("20060102"), 48 * time.Hour
+}
+
+// validateQuotaConfig 验证额度配置
+func validateQuotaConfig(q *QuotaConfig) error {
+	if q == nil {
+		return errors.New("quota config is empty")
+	}
+	if err := q.TenantKey.validate(); err != nil {
+		return err
+	}
+	switch q.GetPeriod() {
+	case QuotaPeriodDaily, QuotaPeriodMonthly:
+	default:
+		return fmt.Errorf("unknown quota period %s", q.Period)
+	}
+	switch q.GetUnit() {
+	case QuotaUnitTokens:
+	case QuotaUnitCost:
+		if q.InputPrice <= 0 && q.OutputPrice <= 0 {
+			return errors.New("input_price or output_price is required for cost quota")
+		}
+	default:
+		return fmt.Errorf("unknown quota unit %s", q.Unit)
+	}
+	switch q.GetFailureMode() {
+	case QuotaFailureAllow, QuotaFailureDeny:
+	default:
+		return fmt.Errorf("unknown quota failure mode %s", q.FailureMode)
+	}
+	if q.Limit < 0 || q.InputPrice < 0 || q.OutputPrice < 0 || q.WarnPercent < 0 || q.WarnPercent > 100 {
+		return errors.New("invalid quota config")
+	}
+	for key, limit := range q.Overrides {
+		if limit < 0 {
+			return fmt.Errorf("negative quota override %s", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+// 租户标识来源
+const (
+	// KeySourceAPIKey 以 API Key 的 SHA-256 摘要作为租户标识
+	KeySourceAPIKey = "api_key"
+	// KeySourceHeader 以租户请求头作为租户标识
+	KeySourceHeader = "header"
+)
+
+// TenantKey 租户标识配置，限流和额度按租户标识统计
+type TenantKey struct {
+	// KeySource 租户标识来源 (api_key, header)，默认 api_key
+	KeySource string `json:"key_source,omitempty"`
+	// KeyHeader 租户标识请求头，KeySource 为 header 时使用，默认 x-tenant-id
+	KeyHeader string `json:"key_header,omitempty"`
+}
+
+// GetKey 获取请求的租户标识
+func (t *TenantKey) GetKey(headers api.RequestHeaderMap) string {
+	if t.KeySource == KeySourceHeader {
+		header := t.KeyHeader
+		if header == "" {
+			header = DefaultTenantHeader
+		}
+		tenant, _ := headers.Get(header)
+		return tenant
+	}
+	if apiKey := GetAPIKey(headers); apiKey != "" {
+		return HashAPIKey(apiKey)
+	}
+	return ""
+}
+
+// validate 验证租户标识配置
+func (t *TenantKey) validate() error {
+	switch t.KeySource {
+	case "", KeySourceAPIKey, KeySourceHeader:
+		return nil
+	default:
+		return fmt.Errorf("unknown key source %s", t.KeySource)
+	}
+}
+
+// RateLimitConfig 模型的限流配置
+type RateLimitConfig struct {
+	TenantKey
+	// Mode 限流模式 (local, shared)，默认 local
+	Mode string `json:"mode,omitempty"`
+	// RequestsPerMinute 每个租户每分钟请求数，0 表示不限制
+	RequestsPerMinute int32 `json:"requests_per_minute,omitempty"`
+	// TokensPerMinute 每个租户每分钟 Token 数（输入 + 输出），0 表示不限制
+	TokensPerMinute int32 `json:"tokens_per_minute,omitempty"`
+	// Overrides 按租户标识覆盖的配额
+	Overrides map[string]*RateLimitQuota `json:"overrides,omitempty"`
+}
+
+// RateLimitQuota 限流配额
+type RateLimitQuota struct {
+	// RequestsPerMinute 每分钟请求数，0 表示不限制
+	RequestsPerMinute int32 `json:"requests_per_minute,omitempty"`
+	// TokensPerMinute 每分钟 Token 数，0 表示不限制
+	TokensPerMinute int32 `json:"tokens_per_minute,omitempty"`
+}
+
+// GetQuota 获取租户的限流配额
+func (r *RateLimitConfig) GetQuota(key string) *RateLimitQuota {
+	if quota, ok := r.Overrides[key]; ok && quota != nil {
+		return quota
+	}
+	return &RateLimitQuota{
+		RequestsPerMinute: r.RequestsPerMinute,
+		TokensPerMinute:   r.TokensPerMinute,
+	}
+}
+
+// validateRateLimitConfig 验证限流配置
+func validateRateLimitConfig(r *RateLimitConfig) error {
+	if r == nil {
+		return errors.New("rate limit config is empty")
+	}
+	if err := r.TenantKey.validate(); err != nil {
+		return err
+	}
+	if r.RequestsPerMinute < 0 || r.TokensPerMinute < 0 {
+		return errors.New("negative rate limit")
+	}
+	for key, quota := range r.Overrides {
+		if quota == nil || quota.RequestsPerMinute < 0 || quota.TokensPerMinute < 0 {
+			return fmt.Errorf("invalid rate limit override %s", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+)
+
+// DefaultSessionHeader 默认的会话标识请求头
+const DefaultSessionHeader = "x-session-id"
+
+// GetSessionHeader 获取会话标识请求头
+func (c *Config) GetSessionHeader() string {
+	if c.SessionHeader == "" {
+		return DefaultSessionHeader
+	}
+	return c.SessionHeader
+}
+
+// IsSplit 判断规则是否按权重在子集间分流
+// 任一子集配置了权重时，每个请求只选择一个子集
+func (r *Rule) IsSplit() bool {
+	for _, s := range r.Subset {
+		if s.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ChooseSplit 按权重选择子集
+// 有会话标识时按会话标识哈希选择，同一会话总是选中同一子集；否则随机选择
+func (r *Rule) ChooseSplit(sessionKey string) *Subset {
+	var total int64
+	for _, s := range r.Subset {
+		total += int64(s.Weight)
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	var n int64
+	if sessionKey != "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(sessionKey))
+		n = int64(h.Sum64() % uint64(total))
+	} else {
+		n = rand.Int63n(total)
+	}
+
+	for _, s := range r.Subset {
+		if n < int64(s.Weight) {
+			return s
+		}
+		n -= int64(s.Weight)
+	}
+	return nil
+}
+
+// WithSplit 返回只包含选中子集的规则副本
+// 子集配置的 cluster、backend 覆盖规则的配置
+func (r *Rule) WithSplit(subset *Subset) *Rule {
+	split := *r
+	split.Subset = []*Subset{subset}
+	if subset.Cluster != "" {
+		split.Cluster = subset.Cluster
+	}
+	if subset.Backend != "" {
+		split.Backend = subset.Backend
+	}
+	return &split
+}
+
+// validateSplit 验证规则的分流配置
+func (r *Rule) validateSplit() error {
+	if !r.IsSplit() {
+		for _, s := range r.Subset {
+			if s.Cluster != "" || s.Backend != "" {
+				return fmt.Errorf("subset %s: cluster and backend require weight", s.Name)
+			}
+		}
+		return nil
+	}
+
+	for _, s := range r.Subset {
+		if s.Weight < 0 {
+			return fmt.Errorf("subset %s: weight must be non-negative", s.Name)
+		}
+		if s.Weight > 0 && s.Cluster == "" && r.Cluster == "" {
+			return fmt.Errorf("subset %s: cluster is required", s.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestChooseSplit(t *testing.T) {
+	const sessions = 10000
+
+	tests := []struct {
+		name    string
+		weights []int32
+		// session 为 true 时使用会话标识选择
+		session bool
+	}{
+		{name: "no weights", weights: []int32{0, 0}},
+		{name: "single subset", weights: []int32{100}, session: true},
+		{name: "weighted by session", weights: []int32{80, 20}, session: true},
+		{name: "zero weight never chosen", weights: []int32{50, 0, 50}, session: true},
+		{name: "weighted without session", weights: []int32{30, 70}},
+		{name: "uneven weights", weights: []int32{1, 2, 7}, session: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &Rule{}
+			var total int32
+			for i, w := range tt.weights {
+				rule.Subset = append(rule.Subset, &Subset{Name: fmt.Sprintf("s%d", i), Weight: w})
+				total += w
+			}
+
+			counts := make(map[string]int)
+			for i := 0; i < sessions; i++ {
+				key := ""
+				if tt.session {
+					key = fmt.Sprintf("session-%d", i)
+				}
+				got := rule.ChooseSplit(key)
+				if total == 0 {
+					if got != nil {
+						t.Fatalf("ChooseSplit() = %s, want nil without weights", got.Name)
+					}
+					continue
+				}
+				if got == nil {
+					t.Fatal("ChooseSplit() = nil")
+				}
+				counts[got.Name]++
+
+				// 同一会话总是选中同一子集
+				if tt.session {
+					if again := rule.ChooseSplit(key); again != got {
+						t.Fatalf("ChooseSplit(%q) = %s then %s", key, got.Name, again.Name)
+					}
+				}
+			}
+			if total == 0 {
+				return
+			}
+
+			for _, s := range rule.Subset {
+				want := float64(sessions) * float64(s.Weight) / float64(total)
+				if got := float64(counts[s.Name]); math.Abs(got-want) > 0.05*sessions {
+					t.Errorf("subset %s chosen %d times, want about %.0f", s.Name, counts[s.Name], want)
+				}
+				if s.Weight == 0 && counts[s.Name] > 0 {
+					t.Errorf("zero weight subset %s chosen %d times", s.Name, counts[s.Name])
+				}
+			}
+		})
+	}
+}
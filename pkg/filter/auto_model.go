@@ -0,0 +1,139 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/config"
+	"github.com/istio-llm-filter/pkg/router"
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+const (
+	// AutoModelHeader 请求虚拟模型时返回实际选择的模型的响应头
+	AutoModelHeader = "x-llm-auto-model"
+	// AutoReasonHeader 请求虚拟模型时返回选择原因（分类器名称或 default）的响应头
+	AutoReasonHeader = "x-llm-auto-reason"
+)
+
+// routeAutoModel 记录虚拟模型的选择结果，并按实际模型重新匹配路由规则
+func (f *Filter) routeAutoModel(reqData *types.RequestData, model, reason string) (*types.RequestData, error) {
+	routed, err := f.transcoder.RemapRequest(f.reqHeaders, model, f.claims)
+	if err != nil {
+		return nil, fmt.Errorf("auto model %s: %w", reqData.ModelName, err)
+	}
+	routed.PromptContext = reqData.PromptContext
+
+	f.autoModel = reqData.ModelName
+	f.autoReason = reason
+	logItems := f.transcoder.GetLLMLogItems()
+	logItems.ModelName = routed.ModelName
+	logItems.Split = routed.Split
+
+	api.LogInfof("[TraceID: %s] auto model %s routed to %s, reason=%s",
+		f.traceId, f.autoModel, routed.ModelName, reason)
+	return routed, nil
+}
+
+// classifyAndRoute 调用分类模型选择实际模型后继续处理请求
+// 在独立协程中执行，通过 DecoderFilterCallbacks 恢复请求处理
+func (f *Filter) classifyAndRoute(reqData *types.RequestData, auto *config.AutoModelConfig) {
+	decoderCallbacks := f.callbacks.DecoderFilterCallbacks()
+	defer decoderCallbacks.RecoverPanic()
+
+	model, reason := f.classifyByLLM(reqData, auto)
+
+	routed, status := f.admitClassified(reqData, model, reason)
+	if status != api.Continue {
+		return
+	}
+	if status := f.chainOrBalance(routed); status == api.Continue {
+		decoderCallbacks.Continue(api.Continue)
+	}
+}
+
+// admitClassified 按分类结果重新匹配路由规则并完成准入
+// 持有 destroyMu 执行：客户端已断开时不再准入，否则 OnDestroy 等待准入完成后再释放优先级并发数、结算限流配额和退还预占额度
+func (f *Filter) admitClassified(reqData *types.RequestData, model, reason string) (*types.RequestData, api.StatusType) {
+	f.destroyMu.Lock()
+	defer f.destroyMu.Unlock()
+	if f.isDestroyed {
+		api.LogInfof("[TraceID: %s] client disconnected while classifying", f.traceId)
+		return nil, api.Running
+	}
+
+	routed, err := f.routeAutoModel(reqData, model, reason)
+	if err != nil {
+		f.badRequest(err)
+		return nil, api.LocalReply
+	}
+	return routed, f.admitRequest(routed)
+}
+
+// classifyByLLM 调用分类模型，按返回的标签选择实际模型
+// 分类模型不可用、超时或返回未知标签时使用 default 模型
+func (f *Filter) classifyByLLM(reqData *types.RequestData, auto *config.AutoModelConfig) (string, string) {
+	llm := auto.LLM
+	prompt := promptContent(reqData)
+
+	label, cached, err := f.callClassifier(reqData.ModelName, llm, prompt)
+	if err != nil {
+		api.LogWarnf("[TraceID: %s] auto model %s classifier %s failed, use default: %v",
+			f.traceId, reqData.ModelName, llm.Model, err)
+		return auto.Default, config.AutoReasonDefault
+	}
+
+	model, ok := llm.ModelForLabel(label)
+	if !ok {
+		api.LogWarnf("[TraceID: %s] auto model %s classifier %s returned unknown label %q, use default",
+			f.traceId, reqData.ModelName, llm.Model, label)
+		return auto.Default, config.AutoReasonDefault
+	}
+
+	api.LogDebugf("[TraceID: %s] auto model %s classified as %s, cached=%t", f.traceId, reqData.ModelName, label, cached)
+	return model, config.AutoReasonLLMPrefix + label
+}
+
+// callClassifier 按分类模型的路由规则选择主机并发送分类请求
+func (f *Filter) callClassifier(autoModel string, llm *config.LLMClassifierConfig, prompt []byte) (string, bool, error) {
+	host, modelName, err := f.sideCallTarget(llm.Model)
+	if err != nil {
+		return "", false, err
+	}
+
+	ctx, cancel := f.destroyContext()
+	defer cancel()
+	return router.GetRouter().Classify(ctx, &router.Request{
+		TraceId:      f.traceId,
+		Host:         host,
+		ModelName:    modelName,
+		SystemPrompt: llm.SystemPrompt(),
+		Prompt:       llm.TruncatePrompt(prompt),
+		Timeout:      llm.GetTimeout(),
+		CacheKey:     router.CacheKey(autoModel, prompt),
+		CacheTTL:     llm.GetCacheTTL(),
+	})
+}
+
+// promptContent 获取用于分类的 Prompt 文本
+func promptContent(reqData *types.RequestData) []byte {
+	if reqData.PromptContext == nil {
+		return nil
+	}
+	return reqData.PromptContext.PromptContent
+}
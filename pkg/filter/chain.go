@@ -0,0 +1,145 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/completion"
+	"github.com/istio-llm-filter/pkg/config"
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// ChainStepHeader 链式处理步骤请求携带的请求头，值为步骤名称，后端可据此区分链式处理流量
+const ChainStepHeader = "x-llm-chain-step"
+
+// runChainAndBalance 执行链式处理的预处理步骤，改写请求后继续选择主机
+// 在独立协程中执行，通过 DecoderFilterCallbacks 恢复请求处理
+func (f *Filter) runChainAndBalance(reqData *types.RequestData, chainConfig *config.ChainConfig) {
+	decoderCallbacks := f.callbacks.DecoderFilterCallbacks()
+	defer decoderCallbacks.RecoverPanic()
+
+	ctx, cancel := f.destroyContext()
+	defer cancel()
+
+	promptCtx, err := f.runChain(ctx, reqData.ChainName, chainConfig)
+	if ctx.Err() != nil {
+		api.LogInfof("[TraceID: %s] client disconnected while running chain %s", f.traceId, reqData.ChainName)
+		return
+	}
+	if err != nil {
+		f.chainStepFailed(err)
+		return
+	}
+
+	if promptCtx != nil {
+		reqData.PromptContext = promptCtx
+	}
+	if status := f.balanceRequest(reqData); status == api.Continue {
+		decoderCallbacks.Continue(api.Continue)
+	}
+}
+
+// runChain 按顺序执行预处理步骤
+// 每个步骤以当前的用户消息为输入，输出按配置替换用户消息或作为 system 消息插入
+// 所有步骤执行完后改写一次请求体；没有步骤成功时不改写，返回 nil
+// 客户端断开时 ctx 被取消，不再执行后续步骤
+func (f *Filter) runChain(ctx context.Context, chainName string, chainConfig *config.ChainConfig) (*types.PromptMessageContext, error) {
+	logItems := f.transcoder.GetLLMLogItems()
+	userPrompt := f.transcoder.GetUserPrompt()
+	var systemPrompts []string
+	changed := false
+
+	for _, step := range chainConfig.Steps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, stepLog, err := f.runChainStep(ctx, step, userPrompt)
+		logItems.ChainSteps = append(logItems.ChainSteps, stepLog)
+		if err != nil {
+			if step.GetOnError() == config.ChainOnErrorAbort {
+				return nil, fmt.Errorf("chain %s step %s failed: %w", chainName, step.Name, err)
+			}
+			api.LogWarnf("[TraceID: %s] chain %s step %s failed, skip: %v", f.traceId, chainName, step.Name, err)
+			continue
+		}
+
+		switch step.GetOutput() {
+		case config.ChainOutputReplace:
+			userPrompt = result.Content
+		case config.ChainOutputSystem:
+			systemPrompts = append(systemPrompts, result.Content)
+		}
+		changed = true
+	}
+
+	inputTokens, outputTokens := 0, 0
+	for _, stepLog := range logItems.ChainSteps {
+		inputTokens += stepLog.InputTokens
+		outputTokens += stepLog.OutputTokens
+	}
+	api.LogInfof("[TraceID: %s] chain %s completed: steps=%d, input_tokens=%d, output_tokens=%d",
+		f.traceId, chainName, len(logItems.ChainSteps), inputTokens, outputTokens)
+
+	if !changed {
+		return nil, nil
+	}
+	return f.transcoder.RewritePrompt(f.reqHeaders, f.reqBuffer, userPrompt, systemPrompts)
+}
+
+// runChainStep 调用步骤模型并记录步骤日志
+func (f *Filter) runChainStep(ctx context.Context, step *config.ChainStep, input string) (*completion.Response, *types.ChainStepLog, error) {
+	stepLog := &types.ChainStepLog{Name: step.Name, Model: step.Model}
+
+	start := time.Now()
+	result, err := f.callChainStep(ctx, step, input)
+	stepLog.LatencyMs = time.Since(start).Milliseconds()
+	if result != nil {
+		stepLog.InputTokens = result.InputTokens
+		stepLog.OutputTokens = result.OutputTokens
+	}
+	if err != nil {
+		stepLog.Error = err.Error()
+		return nil, stepLog, err
+	}
+
+	api.LogDebugf("[TraceID: %s] chain step %s completed: model=%s, latency=%dms, input_tokens=%d, output_tokens=%d",
+		f.traceId, step.Name, step.Model, stepLog.LatencyMs, stepLog.InputTokens, stepLog.OutputTokens)
+	return result, stepLog, nil
+}
+
+// callChainStep 按步骤模型的路由规则选择主机并调用步骤模型
+func (f *Filter) callChainStep(ctx context.Context, step *config.ChainStep, input string) (*completion.Response, error) {
+	host, modelName, err := f.sideCallTarget(step.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	return completion.GetClient().Call(ctx, &completion.Request{
+		TraceId:      f.traceId,
+		Host:         host,
+		Header:       ChainStepHeader,
+		HeaderValue:  step.Name,
+		ModelName:    modelName,
+		SystemPrompt: step.Prompt,
+		UserPrompt:   input,
+		MaxTokens:    step.GetMaxTokens(),
+		Timeout:      step.GetTimeout(),
+	})
+}
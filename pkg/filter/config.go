@@ -15,12 +15,21 @@
 package filter
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/bytedance/sonic"
 	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
 	"google.golang.org/protobuf/types/known/anypb"
 
+	"github.com/istio-llm-filter/pkg/auth"
 	"github.com/istio-llm-filter/pkg/config"
+	"github.com/istio-llm-filter/pkg/loadbalancer"
 	"github.com/istio-llm-filter/pkg/metadata"
+	"github.com/istio-llm-filter/pkg/mirror"
+	"github.com/istio-llm-filter/pkg/queue"
+	"github.com/istio-llm-filter/pkg/quota"
+	"github.com/istio-llm-filter/pkg/ratelimit"
 )
 
 // ConfigParser 实现 api.StreamFilterConfigParser 接口
@@ -58,6 +67,60 @@ func (p *ConfigParser) Parse(any *anypb.Any, callbacks api.ConfigCallbackHandler
 		return nil, err
 	}
 
+	// 验证负载均衡插件流水线
+	for model, lbConfig := range cfg.LbMappingConfigs {
+		if err := loadbalancer.ValidatePipeline(lbConfig.Pipeline); err != nil {
+			return nil, fmt.Errorf("lb pipeline validation error, model=%s, err=%v", model, err)
+		}
+	}
+
+	// 加载并监听 API Key 文件
+	if cfg.Auth != nil && cfg.Auth.KeyFile != "" {
+		if err := auth.Watch(cfg.Auth.KeyFile); err != nil {
+			return nil, fmt.Errorf("auth config validation error, key_file=%s, err=%v", cfg.Auth.KeyFile, err)
+		}
+	}
+
+	// 验证限流模式和额度存储
+	for model, rateLimit := range cfg.RateLimitRule {
+		if ratelimit.GetLimiter(rateLimit.Mode) == nil {
+			return nil, fmt.Errorf("rate limit config validation error, model=%s, err=unknown mode %s", model, rateLimit.Mode)
+		}
+		// 未配置 Metadata-Center 时共享计数不可用，共享限流会放行所有请求
+		if rateLimit.Mode == ratelimit.ModeShared && !metadata.IsEnabled() {
+			return nil, fmt.Errorf("rate limit config validation error, model=%s, err=shared mode requires metadata center", model)
+		}
+	}
+	for model, quotaConfig := range cfg.QuotaRule {
+		if !quota.HasStore(quotaConfig.Store) {
+			return nil, fmt.Errorf("quota config validation error, model=%s, err=unknown store %s", model, quotaConfig.Store)
+		}
+		if quotaConfig.Store == quota.StoreShared && !metadata.IsEnabled() {
+			return nil, fmt.Errorf("quota config validation error, model=%s, err=shared store requires metadata center", model)
+		}
+	}
+
+	// 注册网关排队队列，注销重新加载后已删除的队列
+	queued := make(map[string]bool)
+	for model, lbConfig := range cfg.LbMappingConfigs {
+		if q := lbConfig.Queue; q != nil {
+			queued[model] = true
+			queue.Register(model, queue.Options{
+				MaxSize:          int(q.MaxSize),
+				MaxWait:          time.Duration(q.MaxWaitMs) * time.Millisecond,
+				DispatchInterval: time.Duration(q.DispatchIntervalMs) * time.Millisecond,
+			}, queue.NewMetrics(callbacks, model))
+		}
+	}
+	queue.Retain(queued)
+
+	// 注册请求镜像指标
+	for model, mapping := range cfg.ModelMappings {
+		if mapping.Mirror != nil {
+			mirror.RegisterMetrics(model, mirror.NewMetrics(callbacks, model))
+		}
+	}
+
 	// 初始化 Metadata-Center 客户端
 	cfg.MC = metadata.GetClientOrNoop()
 
@@ -84,5 +147,6 @@ func Factory(c interface{}, callbacks api.FilterCallbackHandler) api.StreamFilte
 	return &Filter{
 		callbacks: callbacks,
 		config:    cfg,
+		destroyed: make(chan struct{}),
 	}
 }
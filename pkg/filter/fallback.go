@@ -0,0 +1,127 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/config"
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// fallbackTarget 选中的降级目标
+type fallbackTarget struct {
+	ctx       context.Context
+	algorithm types.LoadBalancerType
+	reqData   *types.RequestData
+	host      types.Host
+}
+
+// routeState 路由目标相关的请求状态，尝试降级失败时恢复
+type routeState struct {
+	modelName       string
+	modelKey        string
+	cluster         string
+	backendProtocol string
+	split           string
+	prefillHost     types.Host
+	hostMatchInfo   *types.HostMatchInfo
+}
+
+func (f *Filter) saveRouteState() routeState {
+	return routeState{
+		modelName:       f.modelName,
+		modelKey:        f.modelKey,
+		cluster:         f.cluster,
+		backendProtocol: f.backendProtocol,
+		split:           f.split,
+		prefillHost:     f.prefillHost,
+		hostMatchInfo:   f.hostMatchInfo,
+	}
+}
+
+func (f *Filter) restoreRouteState(state routeState) {
+	f.modelName = state.modelName
+	f.modelKey = state.modelKey
+	f.cluster = state.cluster
+	f.backendProtocol = state.backendProtocol
+	f.split = state.split
+	f.prefillHost = state.prefillHost
+	f.hostMatchInfo = state.hostMatchInfo
+}
+
+// chooseFallback 按顺序尝试模型配置的降级目标
+// 降级到其他模型时重新匹配路由规则，降级目标有可用且未饱和的主机时选中
+// 没有可用的降级目标时恢复原来的路由状态并返回 nil
+// 降级目标继承原模型的准入结果，优先级、限流和额度不按降级模型重新检查，只检查模型访问权限
+func (f *Filter) chooseFallback(reqData *types.RequestData) *fallbackTarget {
+	fallbacks := f.config.FindFallbacks(f.modelKey)
+	if len(fallbacks) == 0 {
+		return nil
+	}
+
+	state := f.saveRouteState()
+	for _, fb := range fallbacks {
+		fbData, err := f.fallbackRequestData(reqData, fb)
+		if err != nil {
+			api.LogWarnf("[TraceID: %s] skip fallback %s: %v", f.traceId, fb.Target(), err)
+			continue
+		}
+
+		f.setRequestData(fbData)
+		ctx := f.initLoadBalanceContext(fbData.LbOptions)
+		algorithm := types.LoadBalancerType(f.config.FindAlgorithm(f.modelKey))
+		host, err := f.chooseBackend(ctx, algorithm)
+		if err != nil || f.hostMatchInfo.Saturated {
+			api.LogInfof("[TraceID: %s] fallback %s unavailable, err=%v, saturated=%t",
+				f.traceId, fb.Target(), err, f.hostMatchInfo.Saturated)
+			continue
+		}
+
+		api.LogInfof("[TraceID: %s] cluster %s unavailable for model %s, fall back to %s",
+			f.traceId, state.cluster, state.modelName, fb.Target())
+		f.fallback = fb.Target()
+		f.transcoder.GetLLMLogItems().Split = fbData.Split
+		return &fallbackTarget{ctx: ctx, algorithm: algorithm, reqData: fbData, host: host}
+	}
+
+	f.restoreRouteState(state)
+	return nil
+}
+
+// fallbackRequestData 构建降级目标的请求信息
+func (f *Filter) fallbackRequestData(reqData *types.RequestData, fb *config.Fallback) (*types.RequestData, error) {
+	if fb.Model == "" {
+		fbData := *reqData
+		fbData.Cluster = fb.Cluster
+		if fb.Backend != "" {
+			fbData.BackendProtocol = fb.Backend
+		}
+		return &fbData, nil
+	}
+
+	fbData, err := f.transcoder.RemapRequest(f.reqHeaders, fb.Model, f.claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkModelAllowed(fbData.ModelName, fbData.ModelKey); err != nil {
+		return nil, fmt.Errorf("fallback model not allowed: %w", err)
+	}
+	fbData.PromptContext = reqData.PromptContext
+	return fbData, nil
+}
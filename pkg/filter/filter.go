@@ -17,18 +17,32 @@ package filter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/ast"
 	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
 	"github.com/google/uuid"
 
+	"github.com/istio-llm-filter/pkg/auth"
+	"github.com/istio-llm-filter/pkg/completion"
 	"github.com/istio-llm-filter/pkg/config"
 	"github.com/istio-llm-filter/pkg/hash"
 	"github.com/istio-llm-filter/pkg/loadbalancer"
+	"github.com/istio-llm-filter/pkg/lora"
 	"github.com/istio-llm-filter/pkg/metadata"
+	"github.com/istio-llm-filter/pkg/predictor"
+	"github.com/istio-llm-filter/pkg/queue"
+	"github.com/istio-llm-filter/pkg/quota"
+	"github.com/istio-llm-filter/pkg/ratelimit"
 	"github.com/istio-llm-filter/pkg/transcoder"
 	_ "github.com/istio-llm-filter/pkg/transcoder/openai" // 注册 OpenAI 转码器
 	"github.com/istio-llm-filter/pkg/types"
@@ -37,8 +51,14 @@ import (
 // Name 过滤器名称
 const Name = "llm-proxy"
 
+// FallbackHeader 降级到其他模型或集群时返回降级目标的响应头
+const FallbackHeader = "x-llm-fallback"
+
 var hostname = os.Getenv("HOSTNAME")
 
+// errQuotaUnavailable 额度存储不可用且 failure_mode 为 deny
+var errQuotaUnavailable = errors.New("quota store unavailable")
+
 // Filter LLM Proxy 过滤器
 // 实现 api.StreamFilter 接口
 type Filter struct {
@@ -57,28 +77,72 @@ type Filter struct {
 
 	// 请求上下文
 	traceId         string
+	tenant          string
+	apiKey          *config.APIKeyConfig
+	claims          config.Claims
 	modelName       string
+	modelKey        string
+	split           string
+	env             string
+	fallback        string
+	autoModel       string
+	autoReason      string
+	sessionKey      string
 	cluster         string
 	serverIp        string
 	backendProtocol string
 	isStream        bool
 	uniqueId        string
 
+	// PD 分离模式下选中的 Prefill 主机
+	prefillHost types.Host
+	// 负载均衡器回填的选中主机信息
+	hostMatchInfo *types.HostMatchInfo
+
 	// Metadata-Center 相关
 	promptLength          int
 	promptHash            []uint64
 	isIncreaseRecorded    bool
 	isPromptLengthDeleted bool
+	isPrefillRecorded     bool
+	isPrefillDeleted      bool
 	promptDecreaseTimer   *time.Timer
 
 	// 响应处理
 	respHeader   api.ResponseHeaderMap
 	dropRespData bool
 
+	// 请求优先级
+	priority      string
+	priorityClass *config.PriorityClass
+	isAdmitted    bool
+
+	// 限流预扣的配额
+	rateLimiter     ratelimit.Limiter
+	rateLimitTicket *ratelimit.Ticket
+	rateLimitQuota  ratelimit.Limit
+
+	// 租户额度
+	quotaConfig   *config.QuotaConfig
+	quotaKey      string
+	quotaTTL      time.Duration
+	quotaUsed     float64
+	quotaLimit    float64
+	quotaReserved float64
+
+	// 网关排队
+	queueWait time.Duration
+
+	// 请求销毁状态，异步协程转发请求前检查，与 OnDestroy 互斥
+	destroyMu   sync.Mutex
+	isDestroyed bool
+	destroyed   chan struct{}
+
 	// 时间统计
 	sendFinishTimestamp int64
 	firstTokenTimestamp int64
 	lastTokenTimestamp  int64
+	streamChunks        int
 }
 
 // UniqueId 获取请求唯一 ID
@@ -129,7 +193,32 @@ func (f *Filter) processRequest() api.StatusType {
 	// 1. 获取 Trace ID
 	f.traceId = getTraceID(headers)
 
-	// 2. 获取转码器
+	// 2. API Key 认证，注入租户标识和请求头
+	if f.config.Auth != nil {
+		key, err := auth.Authenticate(f.config.Auth, headers)
+		if err != nil {
+			f.unauthorized("invalid_api_key", err)
+			return api.LocalReply
+		}
+		f.setAPIKey(headers, key)
+	}
+
+	// 读取已验证的 JWT Claims，用于规则匹配和模型授权
+	if f.config.JWT != nil {
+		claims, err := auth.ExtractClaims(f.config.JWT, f.callbacks, headers)
+		if err != nil {
+			f.unauthorized("invalid_token", err)
+			return api.LocalReply
+		}
+		f.setClaims(headers, claims)
+	}
+
+	// 解析环境标识并写入环境请求头，用于按环境匹配路由规则
+	if f.config.Env != nil {
+		f.setEnv(headers, f.config.Env.Resolve(headers, f.apiKey))
+	}
+
+	// 3. 获取转码器
 	inputProtocol := f.config.GetProtocol()
 	factory := transcoder.GetFactory(inputProtocol)
 	if factory == nil {
@@ -138,36 +227,239 @@ func (f *Filter) processRequest() api.StatusType {
 	}
 	f.transcoder = factory(f.callbacks, f.config)
 
-	// 3. 解析请求数据
-	reqData, err := f.transcoder.GetRequestData(headers, buffer.Bytes())
+	// 4. 解析请求数据
+	reqData, err := f.transcoder.GetRequestData(headers, buffer.Bytes(), f.claims)
 	if err != nil {
 		f.badRequest(err)
 		return api.LocalReply
 	}
 
-	// 4. 提取请求信息
+	// 虚拟模型按分类器选择实际模型，再按实际模型匹配路由规则
+	// 规则分类器都不匹配且配置了分类模型时，异步调用分类模型后继续处理
+	if auto := f.config.FindAutoModel(reqData.ModelName); auto != nil {
+		model, reason := auto.Classify(promptContent(reqData))
+		if model == "" {
+			go f.classifyAndRoute(reqData, auto)
+			return api.Running
+		}
+		if reqData, err = f.routeAutoModel(reqData, model, reason); err != nil {
+			f.badRequest(err)
+			return api.LocalReply
+		}
+	}
+
+	return f.routeRequest(reqData)
+}
+
+// routeRequest 按匹配的路由规则完成授权、限流和主机选择
+func (f *Filter) routeRequest(reqData *types.RequestData) api.StatusType {
+	if status := f.admitRequest(reqData); status != api.Continue {
+		return status
+	}
+	return f.chainOrBalance(reqData)
+}
+
+// admitRequest 完成授权、优先级准入、限流和额度预占，通过时返回 api.Continue
+// 准入成功后占用的优先级并发数、限流配额和预占额度在 OnDestroy 中释放或结算
+func (f *Filter) admitRequest(reqData *types.RequestData) api.StatusType {
+	headers := f.reqHeaders
+
+	// 5. 提取请求信息
+	f.setRequestData(reqData)
+
+	api.LogDebugf("[TraceID: %s] request: model=%s, tenant=%s, env=%s, cluster=%s, split=%s, backend=%s",
+		f.traceId, f.modelName, f.tenant, f.env, f.cluster, f.split, f.backendProtocol)
+
+	// 检查 API Key 是否允许访问该模型
+	if err := f.checkModelAllowed(f.modelName, f.modelKey); err != nil {
+		f.forbidden(err)
+		return api.LocalReply
+	}
+
+	// 6. 确定请求优先级，超过该优先级的并发限制时直接丢弃
+	f.priority, f.priorityClass = f.config.Priority.Resolve(headers, f.apiKey, reqData.LbOptions.GetPriority())
+	if f.priorityClass != nil {
+		if err := queue.Admit(f.priority, int(f.priorityClass.MaxInflight)); err != nil {
+			f.priorityShed(err)
+			return api.LocalReply
+		}
+		f.isAdmitted = true
+	}
+
+	// 7. 按租户限流，按预估输入 Token 数预扣配额
+	if err := f.acquireRateLimit(headers, reqData.PromptContext); err != nil {
+		var exceeded *ratelimit.ExceededError
+		if errors.As(err, &exceeded) {
+			f.rateLimited(exceeded)
+			return api.LocalReply
+		}
+		f.badRequest(err)
+		return api.LocalReply
+	}
+
+	// 8. 检查租户在当前统计周期内的额度，按预估的输入 Token 数预占额度
+	if err := f.checkQuota(headers, reqData.PromptContext); err != nil {
+		if errors.Is(err, errQuotaUnavailable) {
+			f.quotaUnavailable(err)
+			return api.LocalReply
+		}
+		f.quotaExceeded(err)
+		return api.LocalReply
+	}
+	return api.Continue
+}
+
+// chainOrBalance 规则配置了链式处理时先执行预处理步骤，否则直接选择主机
+func (f *Filter) chainOrBalance(reqData *types.RequestData) api.StatusType {
+	// 规则配置了链式处理时，异步执行预处理步骤改写请求后继续处理
+	if chainConfig := f.config.FindChain(reqData.ChainName); chainConfig != nil {
+		go f.runChainAndBalance(reqData, chainConfig)
+		return api.Running
+	}
+
+	return f.balanceRequest(reqData)
+}
+
+// balanceRequest 按改写后的 Prompt 选择后端主机，饱和时排队
+func (f *Filter) balanceRequest(reqData *types.RequestData) api.StatusType {
+	// 9. 计算 Prompt 哈希
+	f.computePromptHash(reqData.PromptContext)
+
+	// 10. 初始化负载均衡上下文
+	ctx := f.initLoadBalanceContext(reqData.LbOptions)
+
+	// 11. 选择后端服务器，没有可用主机或所有主机饱和时依次尝试降级目标
+	algorithm := types.LoadBalancerType(f.config.FindAlgorithm(f.modelKey))
+	host, err := f.chooseBackend(ctx, algorithm)
+	if err != nil || f.hostMatchInfo.Saturated {
+		if fb := f.chooseFallback(reqData); fb != nil {
+			ctx, algorithm, reqData, host, err = fb.ctx, fb.algorithm, fb.reqData, fb.host, nil
+		}
+	}
+	if err != nil {
+		api.LogErrorf("[TraceID: %s] choose server failed: %v", f.traceId, err)
+		f.noUpstream(err)
+		return api.LocalReply
+	}
+
+	// 12. 所有主机都达到当前优先级的丢弃阈值时丢弃请求，低优先级请求先被丢弃
+	if f.hostMatchInfo.Overloaded {
+		f.priorityShed(fmt.Errorf("all backends in cluster %s overloaded for priority %s", f.cluster, f.priority))
+		return api.LocalReply
+	}
+
+	// 13. 所有主机都已饱和时进入网关排队，等待后端容量释放
+	if f.hostMatchInfo.Saturated {
+		if q := queue.Get(f.modelKey); q != nil {
+			go f.waitInQueue(q, ctx, algorithm, reqData)
+			return api.Running
+		}
+	}
+
+	return f.dispatchRequest(ctx, algorithm, reqData, host)
+}
+
+// setRequestData 记录路由规则匹配后的请求信息
+func (f *Filter) setRequestData(reqData *types.RequestData) {
 	f.modelName = reqData.ModelName
+	f.modelKey = reqData.ModelKey
+	if f.modelKey == "" {
+		f.modelKey = f.modelName
+	}
 	f.backendProtocol = reqData.BackendProtocol
 	f.cluster = reqData.Cluster
+	f.split = reqData.Split
+	f.env = reqData.Env
+	f.sessionKey = reqData.SessionKey
+}
 
-	api.LogDebugf("[TraceID: %s] request: model=%s, cluster=%s, backend=%s",
-		f.traceId, f.modelName, f.cluster, f.backendProtocol)
+// checkModelAllowed 检查 API Key 和 JWT Claims 是否允许访问模型
+// 模型名、匹配的 model_mapping_rule 键或请求的虚拟模型在允许列表中即可
+func (f *Filter) checkModelAllowed(modelName, modelKey string) error {
+	names := []string{modelName, modelKey}
+	if f.autoModel != "" {
+		names = append(names, f.autoModel)
+	}
+	if f.apiKey != nil && !slices.ContainsFunc(names, f.apiKey.AllowModel) {
+		return fmt.Errorf("The API key is not allowed to access model %s.", modelName)
+	}
+	if f.config.JWT != nil && !slices.ContainsFunc(names, func(name string) bool {
+		return f.config.JWT.AllowModel(f.claims, name)
+	}) {
+		return fmt.Errorf("The token is not allowed to access model %s.", modelName)
+	}
+	return nil
+}
 
-	// 5. 计算 Prompt 哈希
-	f.computePromptHash(reqData.PromptContext)
+// setAPIKey 记录认证成功的 API Key，写入租户标识请求头并注入配置的请求头
+// 注入的请求头在路由规则匹配前生效，客户端传入的同名请求头会被覆盖
+func (f *Filter) setAPIKey(headers api.RequestHeaderMap, key *config.APIKeyConfig) {
+	f.apiKey = key
+	f.tenant = key.Tenant
+	headers.Set(f.config.Auth.GetTenantHeader(), key.Tenant)
+	for _, h := range key.Headers {
+		headers.Set(h.Key, h.Value)
+	}
+}
 
-	// 6. 初始化负载均衡上下文
-	ctx := f.initLoadBalanceContext()
+// setClaims 记录 JWT Claims，API Key 未设置租户时使用 Claim 中的租户标识
+func (f *Filter) setClaims(headers api.RequestHeaderMap, claims config.Claims) {
+	f.claims = claims
+	if f.tenant != "" || f.config.JWT.TenantClaim == "" {
+		return
+	}
+	if tenant := claims.String(f.config.JWT.TenantClaim); tenant != "" {
+		f.tenant = tenant
+		headers.Set(f.config.JWT.GetTenantHeader(), tenant)
+	}
+}
+
+// setEnv 将解析的环境标识写入环境请求头，覆盖客户端传入的值；没有解析到时删除该请求头
+func (f *Filter) setEnv(headers api.RequestHeaderMap, env string) {
+	f.env = env
+	if env == "" {
+		headers.Del(f.config.Env.GetHeader())
+		return
+	}
+	headers.Set(f.config.Env.GetHeader(), env)
+}
+
+// metadataContext 创建调用 Metadata-Center 的 Context，携带 Trace ID 和租户标识
+func (f *Filter) metadataContext() context.Context {
+	ctx := context.WithValue(context.Background(), metadata.CtxKeyTraceId, f.traceId)
+	if f.tenant != "" {
+		ctx = context.WithValue(ctx, metadata.CtxKeyTenant, f.tenant)
+	}
+	return ctx
+}
+
+// chooseBackend 获取集群主机并选择后端服务器
+func (f *Filter) chooseBackend(ctx context.Context, algorithm types.LoadBalancerType) (types.Host, error) {
+	// 重新选择时清空上一次的匹配信息
+	*f.hostMatchInfo = types.HostMatchInfo{}
+	f.prefillHost = nil
 
-	// 7. 选择后端服务器
-	algorithm := types.LoadBalancerType(f.config.GetAlgorithm())
-	hosts := f.getClusterHosts()
+	hosts := f.getClusterHosts(f.cluster)
 	if len(hosts) == 0 {
-		f.noUpstream(fmt.Errorf("no hosts in cluster %s", f.cluster))
-		return api.LocalReply
+		return nil, fmt.Errorf("no hosts in cluster %s", f.cluster)
 	}
+	return f.chooseServer(ctx, algorithm, hosts)
+}
 
-	host, err := loadbalancer.ChooseServer(ctx, f.cluster, algorithm, hosts)
+// dispatchRequest 处理 SLO 策略和 LoRA 适配器加载后转发请求
+func (f *Filter) dispatchRequest(ctx context.Context, algorithm types.LoadBalancerType, reqData *types.RequestData, host types.Host) api.StatusType {
+	var err error
+	if lbConfig := f.config.FindLbMappingRule(f.modelKey); f.hostMatchInfo.SLOUnmet && lbConfig != nil {
+		// 没有主机能满足 SLO，按策略拒绝或溢出
+		slo := lbConfig.SLO
+		switch slo.GetPolicy() {
+		case config.SLOPolicyReject:
+			f.sloUnmet(slo.GetRetryAfterSeconds(), fmt.Errorf("no host in cluster %s can meet slo", f.cluster))
+			return api.LocalReply
+		case config.SLOPolicyOverflow:
+			host, err = f.chooseOverflowServer(ctx, algorithm, slo.OverflowCluster)
+		}
+	}
 	if err != nil {
 		api.LogErrorf("[TraceID: %s] choose server failed: %v", f.traceId, err)
 		f.noUpstream(err)
@@ -175,10 +467,285 @@ func (f *Filter) processRequest() api.StatusType {
 	}
 
 	f.serverIp = host.Ip()
-	api.LogInfof("[TraceID: %s] selected backend: %s for cluster %s",
-		f.traceId, f.serverIp, f.cluster)
+	api.LogInfof("[TraceID: %s] selected backend: %s for cluster %s, affinity=%t",
+		f.traceId, f.serverIp, f.cluster, f.hostMatchInfo.Affinity)
+
+	// 所选主机未加载 LoRA 适配器时，异步动态加载后再转发
+	if f.needLoadLora(reqData.LbOptions, host) {
+		go f.loadLoraAndForward(reqData, host)
+		return api.Running
+	}
+
+	return f.forwardRequest(reqData, host)
+}
 
-	// 8. 转码请求
+// waitInQueue 在网关队列中等待，被放行后重新选择主机
+// 仍然饱和时以原排队顺序重新入队；超时返回 503，客户端断开时直接退出
+// 在独立协程中执行，通过 DecoderFilterCallbacks 恢复请求处理
+func (f *Filter) waitInQueue(q *queue.FairQueue, ctx context.Context, algorithm types.LoadBalancerType, reqData *types.RequestData) {
+	decoderCallbacks := f.callbacks.DecoderFilterCallbacks()
+	defer decoderCallbacks.RecoverPanic()
+
+	w, err := q.Enqueue(f.queueFlow())
+	if err != nil {
+		f.queueFull(err)
+		return
+	}
+	api.LogInfof("[TraceID: %s] all backends saturated, queued for model %s", f.traceId, f.modelName)
+
+	maxWait := q.MaxWait()
+	if f.priorityClass != nil && f.priorityClass.MaxWaitMs > 0 {
+		maxWait = time.Duration(f.priorityClass.MaxWaitMs) * time.Millisecond
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-q.Ready(w):
+			host, err := f.chooseBackend(ctx, algorithm)
+			if err != nil {
+				q.Done(w)
+				f.noUpstream(err)
+				return
+			}
+			if f.hostMatchInfo.Overloaded {
+				q.Done(w)
+				f.priorityShed(fmt.Errorf("all backends in cluster %s overloaded for priority %s", f.cluster, f.priority))
+				return
+			}
+			// 队列已注销时不再排队，直接转发到选中的主机
+			if f.hostMatchInfo.Saturated && q.Requeue(w) {
+				continue
+			}
+			q.Done(w)
+			f.queueWait = time.Since(start)
+			if status := f.dispatchRequest(ctx, algorithm, reqData, host); status == api.Continue {
+				decoderCallbacks.Continue(api.Continue)
+			}
+			return
+		case <-timer.C:
+			q.Cancel(w, true)
+			f.queueTimeout(fmt.Errorf("wait for backend capacity over %s", time.Since(start)))
+			return
+		case <-f.destroyed:
+			q.Cancel(w, false)
+			api.LogInfof("[TraceID: %s] client disconnected while queued", f.traceId)
+			return
+		}
+	}
+}
+
+// acquireRateLimit 按模型的限流配置预扣一个请求和预估的输入 Token 数
+func (f *Filter) acquireRateLimit(headers api.RequestHeaderMap, promptCtx *types.PromptMessageContext) error {
+	rateLimit := f.config.FindRateLimitRule(f.modelKey)
+	if rateLimit == nil {
+		return nil
+	}
+	limiter := ratelimit.GetLimiter(rateLimit.Mode)
+	if limiter == nil {
+		return fmt.Errorf("unknown rate limit mode %s", rateLimit.Mode)
+	}
+
+	key := rateLimit.GetKey(headers)
+	quota := rateLimit.GetQuota(key)
+	f.rateLimitQuota = ratelimit.Limit{
+		RequestsPerMinute: int(quota.RequestsPerMinute),
+		TokensPerMinute:   int(quota.TokensPerMinute),
+	}
+
+	tokens := 0
+	if promptCtx != nil {
+		tokens = ratelimit.EstimateTokens(len(promptCtx.PromptContent))
+	}
+	ctx := f.metadataContext()
+	ticket, err := limiter.Acquire(ctx, f.modelKey+":"+key, f.rateLimitQuota, tokens)
+	if err != nil {
+		return err
+	}
+	f.rateLimiter = limiter
+	f.rateLimitTicket = ticket
+	return nil
+}
+
+// settleRateLimit 用转码器解析的实际 Token 用量（包括链式处理步骤）结算预扣的配额
+// 响应中没有 usage 时保留预估值
+func (f *Filter) settleRateLimit() {
+	if f.rateLimitTicket == nil || f.transcoder == nil {
+		return
+	}
+	inputTokens, outputTokens := f.transcoder.GetLLMLogItems().TotalTokens()
+	actual := inputTokens + outputTokens
+	if actual <= 0 {
+		return
+	}
+
+	ctx := f.metadataContext()
+	f.rateLimiter.Settle(ctx, f.rateLimitTicket, f.rateLimitQuota, actual)
+	api.LogDebugf("[TraceID: %s] settle rate limit: estimated=%d, actual=%d",
+		f.traceId, f.rateLimitTicket.Tokens(), actual)
+}
+
+// checkQuota 检查租户在当前统计周期内的已用额度，未达到上限时按预估的输入 Token 数预占额度
+// 额度存储不可用时按 failure_mode 放行或返回 errQuotaUnavailable
+func (f *Filter) checkQuota(headers api.RequestHeaderMap, promptCtx *types.PromptMessageContext) error {
+	quotaConfig := f.config.FindQuotaRule(f.modelKey)
+	if quotaConfig == nil {
+		return nil
+	}
+	tenant := quotaConfig.GetKey(headers)
+	limit := quotaConfig.GetLimit(tenant)
+	if limit <= 0 {
+		return nil
+	}
+	store := quota.GetStore(quotaConfig.Store)
+	if store == nil {
+		return f.quotaStoreFailed(quotaConfig, fmt.Errorf("unknown quota store %s", quotaConfig.Store))
+	}
+
+	reserved := 0.0
+	if promptCtx != nil {
+		reserved = quotaConfig.Usage(ratelimit.EstimateTokens(len(promptCtx.PromptContent)), 0)
+	}
+	period, ttl := quotaConfig.PeriodKey(time.Now())
+	key := f.modelKey + ":" + tenant + ":" + period
+	ctx := f.metadataContext()
+	used, ok, err := store.Reserve(ctx, key, reserved, limit, ttl)
+	if err != nil {
+		return f.quotaStoreFailed(quotaConfig, err)
+	}
+	if !ok {
+		return fmt.Errorf("You exceeded your %s %s quota: limit %g, used %g.",
+			quotaConfig.GetPeriod(), quotaConfig.GetUnit(), limit, used)
+	}
+
+	f.quotaConfig = quotaConfig
+	f.quotaKey = key
+	f.quotaTTL = ttl
+	f.quotaUsed = used + reserved
+	f.quotaLimit = limit
+	f.quotaReserved = reserved
+	return nil
+}
+
+// quotaStoreFailed 额度存储不可用时按 failure_mode 放行或拒绝请求
+func (f *Filter) quotaStoreFailed(quotaConfig *config.QuotaConfig, err error) error {
+	if quotaConfig.GetFailureMode() == config.QuotaFailureDeny {
+		return fmt.Errorf("%w: %v", errQuotaUnavailable, err)
+	}
+	api.LogWarnf("[TraceID: %s] quota store unavailable, allow request: %v", f.traceId, err)
+	return nil
+}
+
+// setQuotaHeaders 在响应头中返回额度信息，已用额度达到预警百分比时添加预警
+func (f *Filter) setQuotaHeaders(header api.ResponseHeaderMap) {
+	if f.quotaConfig == nil {
+		return
+	}
+	header.Set("x-quota-limit", strconv.FormatFloat(f.quotaLimit, 'f', -1, 64))
+	header.Set("x-quota-remaining", strconv.FormatFloat(math.Max(f.quotaLimit-f.quotaUsed, 0), 'f', -1, 64))
+
+	percent := f.quotaUsed / f.quotaLimit * 100
+	if percent >= float64(f.quotaConfig.GetWarnPercent()) {
+		header.Set("x-quota-warning", fmt.Sprintf("%.0f%% of %s %s quota used",
+			percent, f.quotaConfig.GetPeriod(), f.quotaConfig.GetUnit()))
+	}
+}
+
+// debitQuota 按转码器解析的实际 Token 用量（包括链式处理步骤）与预占额度的差值扣减额度
+// 响应中没有 usage 时按预估的输入 Token 数扣减，请求未转发时退还预占的额度
+func (f *Filter) debitQuota() {
+	if f.quotaConfig == nil {
+		return
+	}
+	store := quota.GetStore(f.quotaConfig.Store)
+	ctx := f.metadataContext()
+	if f.sendFinishTimestamp <= 0 {
+		if f.quotaReserved > 0 {
+			if err := store.Add(ctx, f.quotaKey, -f.quotaReserved, f.quotaTTL); err != nil {
+				api.LogErrorf("[TraceID: %s] refund quota failed: %v", f.traceId, err)
+			}
+		}
+		return
+	}
+
+	inputTokens, outputTokens := 0, 0
+	if f.transcoder != nil {
+		inputTokens, outputTokens = f.transcoder.GetLLMLogItems().TotalTokens()
+	}
+	if inputTokens+outputTokens <= 0 {
+		inputTokens = ratelimit.EstimateTokens(f.promptLength)
+	}
+
+	amount := f.quotaConfig.Usage(inputTokens, outputTokens) - f.quotaReserved
+	if amount == 0 {
+		return
+	}
+	if err := store.Add(ctx, f.quotaKey, amount, f.quotaTTL); err != nil {
+		api.LogErrorf("[TraceID: %s] debit quota failed: %v", f.traceId, err)
+		return
+	}
+	api.LogDebugf("[TraceID: %s] debit quota: key=%s, amount=%g", f.traceId, f.quotaKey, amount)
+}
+
+// queueFlow 获取请求所属的排队流
+// 同一优先级、同一租户的请求属于同一个流，流权重为优先级权重与租户权重之积
+func (f *Filter) queueFlow() queue.Flow {
+	var queueConfig *config.QueueConfig
+	if lbConfig := f.config.FindLbMappingRule(f.modelKey); lbConfig != nil {
+		queueConfig = lbConfig.Queue
+	}
+	tenant, _ := f.reqHeaders.Get(queueConfig.GetTenantHeader())
+	flow := queue.Flow{
+		Key:    f.priority + "/" + tenant,
+		Weight: queueConfig.GetTenantWeight(tenant),
+		Class:  f.priority,
+	}
+	if class := f.priorityClass; class != nil {
+		flow.Level = int(class.Level)
+		flow.MaxQueued = int(class.MaxQueueSize)
+		if class.QueueWeight > 0 {
+			flow.Weight *= int(class.QueueWeight)
+		}
+	}
+	return flow
+}
+
+// chooseServer 选择后端服务器
+// PD 分离模式下同时选择 Prefill 主机，返回的是 Decode 主机
+func (f *Filter) chooseServer(ctx context.Context, algorithm types.LoadBalancerType, hosts []types.Host) (types.Host, error) {
+	if algorithm != types.PDDisaggLB {
+		return loadbalancer.ChooseServer(ctx, f.cluster, algorithm, hosts)
+	}
+
+	prefill, decode, err := loadbalancer.ChooseServerPair(ctx, f.cluster, algorithm, hosts)
+	if err != nil {
+		return nil, err
+	}
+	f.prefillHost = prefill
+	api.LogInfof("[TraceID: %s] selected prefill backend: %s for cluster %s",
+		f.traceId, prefill.Address(), f.cluster)
+	return decode, nil
+}
+
+// forwardRequest 转码请求并转发到选中的主机
+// 异步协程中调用时客户端可能已断开，此时不再转发，避免 OnDestroy 之后记录请求统计
+func (f *Filter) forwardRequest(reqData *types.RequestData, host types.Host) api.StatusType {
+	f.destroyMu.Lock()
+	defer f.destroyMu.Unlock()
+	if f.isDestroyed {
+		api.LogInfof("[TraceID: %s] client disconnected before forwarding", f.traceId)
+		return api.Running
+	}
+
+	headers := f.reqHeaders
+	buffer := f.reqBuffer
+
+	// 1. 按采样比例镜像请求到影子集群，转码前复制原始请求体
+	f.mirrorRequest()
+
+	// 2. 转码请求
 	proxyModelName := f.modelName
 	if reqData.LbOptions != nil && reqData.LbOptions.GetLoraID() != "" {
 		proxyModelName = reqData.LbOptions.GetLoraID()
@@ -192,24 +759,103 @@ func (f *Filter) processRequest() api.StatusType {
 
 	f.isStream = reqCtx.IsStream
 
-	// 9. 更新 Metadata-Center（异步）
+	// 3. 更新 Metadata-Center（异步）
 	f.addRequest()
 
-	// 10. 记录发送完成时间
+	// 4. 记录发送完成时间
 	f.sendFinishTimestamp = time.Now().UnixMicro()
 
-	// 11. 设置上游主机
+	// 5. 设置上游主机
 	f.setUpstreamHost(headers, host)
+	if f.prefillHost != nil {
+		if err := f.setPrefillHost(headers, buffer); err != nil {
+			f.badRequest(err)
+			return api.LocalReply
+		}
+	}
 
 	return api.Continue
 }
 
+// chooseOverflowServer 从溢出集群中选择后端服务器，不再检查 SLO
+func (f *Filter) chooseOverflowServer(ctx context.Context, algorithm types.LoadBalancerType, cluster string) (types.Host, error) {
+	api.LogInfof("[TraceID: %s] slo unmet in cluster %s, overflow to cluster %s",
+		f.traceId, f.cluster, cluster)
+
+	f.cluster = cluster
+	f.prefillHost = nil
+	hosts := f.getClusterHosts(f.cluster)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts in overflow cluster %s", f.cluster)
+	}
+
+	ctx = context.WithValue(ctx, types.KeySLOTTFT, time.Duration(0))
+	ctx = context.WithValue(ctx, types.KeySLOTPOT, time.Duration(0))
+	return f.chooseServer(ctx, algorithm, hosts)
+}
+
+// needLoadLora 判断是否需要在所选主机上动态加载 LoRA 适配器
+func (f *Filter) needLoadLora(lbOptions *types.LoadBalancerOptions, host types.Host) bool {
+	if lbOptions.GetLoraID() == "" || lbOptions.GetLoraPath() == "" {
+		return false
+	}
+	return !lora.GetRegistry().Has(host.Ip(), lbOptions.GetLoraID())
+}
+
+// loadLoraAndForward 动态加载 LoRA 适配器，成功后继续转发请求
+// 在独立协程中执行，通过 DecoderFilterCallbacks 恢复请求处理
+func (f *Filter) loadLoraAndForward(reqData *types.RequestData, host types.Host) {
+	decoderCallbacks := f.callbacks.DecoderFilterCallbacks()
+	defer decoderCallbacks.RecoverPanic()
+
+	opts := reqData.LbOptions
+	api.LogInfof("[TraceID: %s] loading lora adapter %s on %s",
+		f.traceId, opts.GetLoraID(), host.Address())
+
+	ctx, cancel := f.destroyContext()
+	defer cancel()
+	err := lora.GetLoader().Ensure(ctx, host, opts.GetLoraID(), opts.GetLoraPath(), opts.LoraLoadTimeout)
+	if errors.Is(err, context.Canceled) {
+		api.LogInfof("[TraceID: %s] client disconnected while loading lora adapter", f.traceId)
+		return
+	}
+	if err != nil {
+		f.loraLoadFailed(err)
+		return
+	}
+
+	if status := f.forwardRequest(reqData, host); status == api.Continue {
+		decoderCallbacks.Continue(api.Continue)
+	}
+}
+
+// destroyContext 创建请求销毁时取消的 Context，用于异步协程中的外部调用
+func (f *Filter) destroyContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-f.destroyed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 // EncodeHeaders 处理响应头
 func (f *Filter) EncodeHeaders(header api.ResponseHeaderMap, endStream bool) api.StatusType {
 	// 添加通用响应头
 	if hostname != "" {
 		header.Add("x-llm-proxy-via", hostname)
 	}
+	f.setQuotaHeaders(header)
+	if f.fallback != "" {
+		header.Set(FallbackHeader, f.fallback)
+	}
+	if f.autoModel != "" {
+		header.Set(AutoModelHeader, f.modelName)
+		header.Set(AutoReasonHeader, f.autoReason)
+	}
 
 	status, _ := header.Status()
 	if status >= http.StatusBadRequest {
@@ -255,6 +901,7 @@ func (f *Filter) EncodeData(buffer api.BufferInstance, endStream bool) api.Statu
 
 	if f.isStream {
 		// 流式响应，逐块处理
+		f.streamChunks++
 		return f.processResponseData(f.respHeader, buffer)
 	}
 
@@ -276,15 +923,38 @@ func (f *Filter) EncodeTrailers(trailers api.ResponseTrailerMap) api.StatusType
 
 // OnDestroy 请求销毁回调
 func (f *Filter) OnDestroy(reason api.DestroyReason) {
+	// 标记请求已销毁，取消仍在排队、加载 LoRA 或执行链式处理的本请求
+	f.destroyMu.Lock()
+	f.isDestroyed = true
+	close(f.destroyed)
+	f.destroyMu.Unlock()
+
 	// 删除请求统计
 	if f.isIncreaseRecorded {
 		f.decreaseRequest()
 	}
 
+	// 用实际 TPOT 更新预测模型
+	f.observeTPOT()
+
+	// 按实际 Token 用量结算限流配额和租户额度
+	f.settleRateLimit()
+	f.debitQuota()
+
+	// 释放优先级并发数
+	if f.isAdmitted {
+		queue.Release(f.priority)
+	}
+
+	// 通知排队中的请求有后端容量释放
+	if q := queue.Get(f.modelKey); q != nil && f.isIncreaseRecorded {
+		q.Notify()
+	}
+
 	// 记录日志指标
 	ttft := f.getTTFT()
-	api.LogInfof("[TraceID: %s] request completed: model=%s, backend=%s, ttft=%dms, reason=%d",
-		f.traceId, f.modelName, f.serverIp, ttft.Milliseconds(), reason)
+	api.LogInfof("[TraceID: %s] request completed: model=%s, tenant=%s, env=%s, priority=%s, cluster=%s, split=%s, fallback=%s, auto=%s, auto_reason=%s, backend=%s, ttft=%dms, queue_wait=%dms, reason=%d",
+		f.traceId, f.modelName, f.tenant, f.env, f.priority, f.cluster, f.split, f.fallback, f.autoModel, f.autoReason, f.serverIp, ttft.Milliseconds(), f.queueWait.Milliseconds(), reason)
 }
 
 // 内部方法
@@ -305,6 +975,115 @@ func (f *Filter) noUpstream(err error) {
 	}, 0, "no_upstream")
 }
 
+func (f *Filter) loraLoadFailed(err error) {
+	api.LogInfof("[TraceID: %s] lora load failed: %v", f.traceId, err)
+	errCode, status := &types.ErrLoraLoad, http.StatusServiceUnavailable
+	if errors.Is(err, lora.ErrLoadTimeout) {
+		errCode, status = &types.ErrLoraLoadTimeout, http.StatusGatewayTimeout
+	}
+	body := types.FormatGatewayResponse(errCode, f.traceId, err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(status, string(body), map[string][]string{
+		"content-type": {"application/json"},
+	}, 0, errCode.Type)
+}
+
+func (f *Filter) chainStepFailed(err error) {
+	api.LogInfof("[TraceID: %s] chain step failed: %v", f.traceId, err)
+	errCode := &types.ErrChainStep
+	if errors.Is(err, completion.ErrTimeout) {
+		errCode = &types.ErrChainStepTimeout
+	}
+	body := types.FormatGatewayResponse(errCode, f.traceId, err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(errCode.Code, string(body), map[string][]string{
+		"content-type": {"application/json"},
+	}, 0, errCode.Type)
+}
+
+func (f *Filter) sloUnmet(retryAfter int, err error) {
+	api.LogInfof("[TraceID: %s] slo unmet: %v", f.traceId, err)
+	body := types.FormatGatewayResponse(&types.ErrSLOUnmet, f.traceId, err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusTooManyRequests, string(body), map[string][]string{
+		"content-type": {"application/json"},
+		"retry-after":  {strconv.Itoa(retryAfter)},
+	}, 0, "slo_unmet")
+}
+
+func (f *Filter) queueFull(err error) {
+	api.LogInfof("[TraceID: %s] queue full: %v", f.traceId, err)
+	body := types.FormatGatewayResponse(&types.ErrQueueFull, f.traceId, err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusTooManyRequests, string(body), map[string][]string{
+		"content-type": {"application/json"},
+		"retry-after":  {"1"},
+	}, 0, "queue_full")
+}
+
+func (f *Filter) queueTimeout(err error) {
+	api.LogInfof("[TraceID: %s] queue timeout: %v", f.traceId, err)
+	body := types.FormatGatewayResponse(&types.ErrQueueTimeout, f.traceId, err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusServiceUnavailable, string(body), map[string][]string{
+		"content-type": {"application/json"},
+	}, 0, "queue_timeout")
+}
+
+// rateLimited 返回 OpenAI 格式的 429 响应
+func (f *Filter) rateLimited(err *ratelimit.ExceededError) {
+	api.LogInfof("[TraceID: %s] rate limited: %v", f.traceId, err)
+	body := types.FormatOpenAIResponse(err.Kind, "rate_limit_exceeded", err.Error())
+	retryAfter := int(math.Ceil(err.RetryAfter.Seconds()))
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusTooManyRequests, string(body), map[string][]string{
+		"content-type":                  {"application/json"},
+		"retry-after":                   {strconv.Itoa(max(retryAfter, 1))},
+		"x-ratelimit-limit-requests":    {strconv.Itoa(f.rateLimitQuota.RequestsPerMinute)},
+		"x-ratelimit-limit-tokens":      {strconv.Itoa(f.rateLimitQuota.TokensPerMinute)},
+		"x-ratelimit-reset-" + err.Kind: {err.RetryAfter.Round(time.Millisecond).String()},
+	}, 0, "rate_limited")
+}
+
+// quotaExceeded 返回 OpenAI 格式的 429 额度不足响应
+func (f *Filter) quotaExceeded(err error) {
+	api.LogInfof("[TraceID: %s] quota exceeded: %v", f.traceId, err)
+	body := types.FormatOpenAIResponse("insufficient_quota", "insufficient_quota", err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusTooManyRequests, string(body), map[string][]string{
+		"content-type": {"application/json"},
+	}, 0, "quota_exceeded")
+}
+
+// quotaUnavailable 额度存储不可用且 failure_mode 为 deny 时返回 OpenAI 格式的 503 响应
+func (f *Filter) quotaUnavailable(err error) {
+	api.LogInfof("[TraceID: %s] quota unavailable: %v", f.traceId, err)
+	body := types.FormatOpenAIResponse("api_error", "quota_unavailable", err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusServiceUnavailable, string(body), map[string][]string{
+		"content-type": {"application/json"},
+	}, 0, "quota_unavailable")
+}
+
+// unauthorized 返回 OpenAI 格式的 401 响应
+func (f *Filter) unauthorized(code string, err error) {
+	api.LogInfof("[TraceID: %s] unauthorized: %v", f.traceId, err)
+	body := types.FormatOpenAIResponse("invalid_request_error", code, err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusUnauthorized, string(body), map[string][]string{
+		"content-type": {"application/json"},
+	}, 0, "unauthorized")
+}
+
+// forbidden 返回 OpenAI 格式的 403 响应
+func (f *Filter) forbidden(err error) {
+	api.LogInfof("[TraceID: %s] forbidden: tenant=%s, %v", f.traceId, f.tenant, err)
+	body := types.FormatOpenAIResponse("invalid_request_error", "model_not_allowed", err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusForbidden, string(body), map[string][]string{
+		"content-type": {"application/json"},
+	}, 0, "forbidden")
+}
+
+func (f *Filter) priorityShed(err error) {
+	api.LogInfof("[TraceID: %s] priority shed: %v", f.traceId, err)
+	body := types.FormatGatewayResponse(&types.ErrPriorityShed, f.traceId, err.Error())
+	f.callbacks.DecoderFilterCallbacks().SendLocalReply(http.StatusServiceUnavailable, string(body), map[string][]string{
+		"content-type": {"application/json"},
+		"retry-after":  {"1"},
+	}, 0, "priority_shed")
+}
+
 func (f *Filter) badResponse(err error) {
 	api.LogInfof("[TraceID: %s] bad response: %v", f.traceId, err)
 	body := types.FormatGatewayResponse(&types.ErrInferenceServer, f.traceId, err.Error())
@@ -313,26 +1092,88 @@ func (f *Filter) badResponse(err error) {
 	}, 0, "bad_response")
 }
 
-func (f *Filter) initLoadBalanceContext() context.Context {
+func (f *Filter) initLoadBalanceContext(lbOptions *types.LoadBalancerOptions) context.Context {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, types.KeyTraceId, f.traceId)
 	ctx = context.WithValue(ctx, types.KeyModelName, f.modelName)
 	ctx = context.WithValue(ctx, types.KeyClusterName, f.cluster)
 	ctx = context.WithValue(ctx, metadata.CtxKeyTraceId, f.traceId)
+	if f.tenant != "" {
+		ctx = context.WithValue(ctx, metadata.CtxKeyTenant, f.tenant)
+	}
+	ctx = context.WithValue(ctx, types.KeyPromptLength, f.promptLength)
+
+	// 负载均衡器选中主机后回填匹配信息
+	f.hostMatchInfo = &types.HostMatchInfo{}
+	ctx = context.WithValue(ctx, types.KeyHostMatchInfo, f.hostMatchInfo)
 
 	// 设置 Prompt 哈希
 	if len(f.promptHash) > 0 {
 		ctx = context.WithValue(ctx, types.KeyPromptHash, f.promptHash)
 	}
 
+	// 设置 LoRA 适配器
+	if loraID := lbOptions.GetLoraID(); loraID != "" {
+		ctx = context.WithValue(ctx, types.KeyLoraID, loraID)
+	}
+
+	// 设置优先级的候选主机范围和丢弃阈值
+	if class := f.priorityClass; class != nil {
+		if len(class.Selector) > 0 {
+			selector := make(map[string]string, len(class.Selector))
+			if lbOptions != nil {
+				for k, v := range lbOptions.Selector {
+					selector[k] = v
+				}
+			}
+			for k, v := range class.Selector {
+				selector[k] = v
+			}
+			ctx = context.WithValue(ctx, types.KeyLbSelector, selector)
+		}
+		ctx = context.WithValue(ctx, types.KeyShedQueueDepth, int(class.ShedQueueDepth))
+	}
+
 	// 设置负载均衡配置
-	if lbConfig := f.config.FindLbMappingRule(f.modelName); lbConfig != nil {
+	if lbConfig := f.config.FindLbMappingRule(f.modelKey); lbConfig != nil {
 		ctx = context.WithValue(ctx, types.KeyLoadAwareEnable, lbConfig.LoadAwareEnable)
 		ctx = context.WithValue(ctx, types.KeyCacheAwareEnable, lbConfig.CacheAwareEnable)
 		ctx = context.WithValue(ctx, types.KeyCandidatePercent, int(lbConfig.CandidatePercent))
 		ctx = context.WithValue(ctx, types.KeyLoadRequestWeight, int(lbConfig.RequestLoadWeight))
 		ctx = context.WithValue(ctx, types.KeyLoadPrefillWeight, int(lbConfig.PrefillLoadWeight))
 		ctx = context.WithValue(ctx, types.KeyCacheRatioWeight, int(lbConfig.CacheRadioWeight))
+		ctx = context.WithValue(ctx, types.KeyKVUsageWeight, int(lbConfig.KVUsageWeight))
+		ctx = context.WithValue(ctx, types.KeyKVUsageThreshold, int(lbConfig.KVUsageThreshold))
+		ctx = context.WithValue(ctx, types.KeyLoraAffinityWeight, int(lbConfig.LoraAffinityWeight))
+		ctx = context.WithValue(ctx, types.KeyMaxAdaptersPerHost, int(lbConfig.MaxAdaptersPerHost))
+		ctx = context.WithValue(ctx, types.KeyLoraPollInterval, time.Duration(lbConfig.LoraPollIntervalMs)*time.Millisecond)
+		if lbConfig.SelectStrategy != "" {
+			ctx = context.WithValue(ctx, types.KeySelectStrategy, lbConfig.SelectStrategy)
+		}
+		if lbConfig.SoftmaxTemperature > 0 {
+			ctx = context.WithValue(ctx, types.KeySoftmaxTemperature, lbConfig.SoftmaxTemperature)
+		}
+		if lbConfig.ScoreGap > 0 {
+			ctx = context.WithValue(ctx, types.KeyScoreGap, lbConfig.ScoreGap)
+		}
+		if lbConfig.MinCandidates > 0 {
+			ctx = context.WithValue(ctx, types.KeyMinCandidates, int(lbConfig.MinCandidates))
+		}
+		if slo := lbConfig.SLO; slo != nil {
+			ctx = context.WithValue(ctx, types.KeySLOTTFT, time.Duration(slo.TTFTMs)*time.Millisecond)
+			ctx = context.WithValue(ctx, types.KeySLOTPOT, time.Duration(slo.TPOTMs)*time.Millisecond)
+		}
+		if lbConfig.Pipeline != nil {
+			ctx = context.WithValue(ctx, types.KeyLbPipeline, lbConfig.Pipeline)
+		}
+		if q := lbConfig.Queue; q != nil {
+			ctx = context.WithValue(ctx, types.KeyHostQueueDepth, int(q.HostQueueDepth))
+		}
+		if affinity := lbConfig.Affinity; affinity != nil && f.sessionKey != "" {
+			ctx = context.WithValue(ctx, types.KeyAffinityKey, f.sessionKey)
+			ctx = context.WithValue(ctx, types.KeyAffinityMaxQueueDepth, affinity.GetMaxQueueDepth())
+			ctx = context.WithValue(ctx, types.KeyAffinityTTL, affinity.GetTTL())
+		}
 	}
 
 	return ctx
@@ -358,7 +1199,7 @@ func (f *Filter) computePromptHash(promptCtx *types.PromptMessageContext) {
 		f.traceId, f.promptLength, len(f.promptHash))
 }
 
-func (f *Filter) getClusterHosts() []types.Host {
+func (f *Filter) getClusterHosts(cluster string) []types.Host {
 	// TODO: 从 Envoy 集群管理器获取主机列表
 	// 这里需要通过 Envoy API 获取实际的集群端点
 	// 暂时返回空，实际实现需要集成 Envoy 集群发现
@@ -373,6 +1214,35 @@ func (f *Filter) setUpstreamHost(headers api.RequestHeaderMap, host types.Host)
 	}
 }
 
+// setPrefillHost 将 Prefill 主机地址传递给引擎的 PD 分离代理
+// 默认通过请求头传递，配置了请求体字段时同时写入请求体
+func (f *Filter) setPrefillHost(headers api.RequestHeaderMap, buffer api.BufferInstance) error {
+	lbConfig := f.config.FindLbMappingRule(f.modelKey)
+	addr := f.prefillHost.Address()
+	headers.Set(lbConfig.GetPrefillHeader(), addr)
+
+	field := lbConfig.GetPrefillBodyField()
+	if field == "" {
+		return nil
+	}
+	root, err := sonic.Get(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to parse request body: %w", err)
+	}
+	if _, err := root.Set(field, ast.NewString(addr)); err != nil {
+		return fmt.Errorf("failed to set prefill field %s: %w", field, err)
+	}
+	body, err := root.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+	if err := buffer.Set(body); err != nil {
+		return err
+	}
+	headers.Set("content-length", fmt.Sprintf("%d", len(body)))
+	return nil
+}
+
 func (f *Filter) processResponseData(headers api.ResponseHeaderMap, buffer api.BufferInstance) api.StatusType {
 	if f.dropRespData {
 		buffer.Reset()
@@ -414,6 +1284,8 @@ func (f *Filter) recordTokenTime() {
 		f.firstTokenTimestamp = now
 		// 首 Token 到达，删除 Prompt 长度
 		f.deletePromptLength()
+		// 用实际 TTFT 更新预测模型
+		f.observeTTFT()
 	}
 	f.lastTokenTimestamp = now
 }
@@ -428,15 +1300,61 @@ func (f *Filter) getTTFT() time.Duration {
 	return time.Duration(f.firstTokenTimestamp-f.sendFinishTimestamp) * time.Microsecond
 }
 
+// ttftHostIp 决定 TTFT 的主机，PD 分离模式下为 Prefill 主机
+func (f *Filter) ttftHostIp() string {
+	if f.prefillHost != nil {
+		return f.prefillHost.Ip()
+	}
+	return f.serverIp
+}
+
+func (f *Filter) predictTTFT() time.Duration {
+	return predictor.GetTTFTPredictor().Predict(f.modelName, f.ttftHostIp(), f.promptLength, f.hostMatchInfo.QueueDepth)
+}
+
+// observeTTFT 用实际 TTFT 更新预测模型
+// 非流式响应的首个数据块在生成结束后才到达，测得的是完整延迟而不是 TTFT，不参与更新
+func (f *Filter) observeTTFT() {
+	if !f.isStream {
+		return
+	}
+	ttft := f.getTTFT()
+	if ttft <= 0 {
+		return
+	}
+	predictor.GetTTFTPredictor().Observe(f.modelName, f.ttftHostIp(), f.promptLength, f.hostMatchInfo.QueueDepth, ttft)
+}
+
+// observeTPOT 根据首 Token 到末 Token 的耗时计算 TPOT
+// 优先使用响应中的输出 Token 数，缺少 usage 时按数据块数量估算
+// 非流式响应的数据块间隔只是传输耗时，不参与更新
+func (f *Filter) observeTPOT() {
+	if !f.isStream || f.firstTokenTimestamp <= 0 || f.lastTokenTimestamp <= f.firstTokenTimestamp {
+		return
+	}
+	tokens := 0
+	if f.transcoder != nil {
+		tokens = f.transcoder.GetLLMLogItems().OutputTokens
+	}
+	if tokens <= 1 {
+		tokens = f.streamChunks
+	}
+	if tokens <= 1 {
+		return
+	}
+	tpot := time.Duration(f.lastTokenTimestamp-f.firstTokenTimestamp) * time.Microsecond / time.Duration(tokens-1)
+	predictor.GetTPOTPredictor().Observe(f.modelName, f.serverIp, tpot)
+}
+
 func (f *Filter) isLoadAwareEnabled() bool {
-	if lbConfig := f.config.FindLbMappingRule(f.modelName); lbConfig != nil {
+	if lbConfig := f.config.FindLbMappingRule(f.modelKey); lbConfig != nil {
 		return lbConfig.LoadAwareEnable
 	}
 	return metadata.IsEnabled()
 }
 
 func (f *Filter) isCacheAwareEnabled() bool {
-	if lbConfig := f.config.FindLbMappingRule(f.modelName); lbConfig != nil {
+	if lbConfig := f.config.FindLbMappingRule(f.modelKey); lbConfig != nil {
 		return lbConfig.CacheAwareEnable
 	}
 	return metadata.IsCacheEnabled()
@@ -449,9 +1367,17 @@ func (f *Filter) addRequest() {
 		return
 	}
 
-	ctx := context.WithValue(context.Background(), metadata.CtxKeyTraceId, f.traceId)
+	ctx := f.metadataContext()
 	client := metadata.GetClientOrNoop()
-	err := client.AddRequest(ctx, f.UniqueId(), f.cluster, f.serverIp, f.promptLength)
+
+	// PD 分离模式下 Prefill 主机单独统计，Decode 主机不计 Prompt 长度
+	promptLength := f.promptLength
+	if f.prefillHost != nil {
+		promptLength = 0
+		f.addPrefillRequest(ctx, client)
+	}
+
+	err := client.AddRequest(ctx, f.UniqueId(), f.cluster, f.serverIp, promptLength)
 	if err != nil {
 		api.LogErrorf("[TraceID: %s] add request failed: %v", f.traceId, err)
 		return
@@ -459,19 +1385,55 @@ func (f *Filter) addRequest() {
 
 	f.isIncreaseRecorded = true
 	api.LogDebugf("[TraceID: %s] add request: cluster=%s, ip=%s, prompt_length=%d",
-		f.traceId, f.cluster, f.serverIp, f.promptLength)
+		f.traceId, f.cluster, f.serverIp, promptLength)
 
 	// 非流式请求设置定时器删除 Prompt 长度
 	if !f.isStream {
-		// 预估 TTFT 的 1.2 倍后删除
-		ttftMs := estimateTTFT(f.modelName, f.promptLength)
-		delay := time.Duration(ttftMs*12/10) * time.Millisecond
+		// 预测 TTFT 的 1.2 倍后删除
+		ttft := f.predictTTFT()
+		delay := ttft * 12 / 10
 		f.promptDecreaseTimer = time.AfterFunc(delay, func() {
 			f.deletePromptLength()
 		})
 	}
 }
 
+// prefillRequestId Prefill 主机上的请求统计 ID
+func (f *Filter) prefillRequestId() string {
+	return f.UniqueId() + "-prefill"
+}
+
+func (f *Filter) addPrefillRequest(ctx context.Context, client types.MetadataCenter) {
+	ip := f.prefillHost.Ip()
+	err := client.AddRequest(ctx, f.prefillRequestId(), f.cluster, ip, f.promptLength)
+	if err != nil {
+		api.LogErrorf("[TraceID: %s] add prefill request failed: %v", f.traceId, err)
+		return
+	}
+
+	f.isPrefillRecorded = true
+	api.LogDebugf("[TraceID: %s] add prefill request: cluster=%s, ip=%s, prompt_length=%d",
+		f.traceId, f.cluster, ip, f.promptLength)
+}
+
+// deletePrefillRequest Prefill 完成后删除 Prefill 主机上的请求统计
+func (f *Filter) deletePrefillRequest() {
+	if !f.isPrefillRecorded || f.isPrefillDeleted {
+		return
+	}
+
+	ctx := f.metadataContext()
+	client := metadata.GetClientOrNoop()
+	err := client.DeleteRequest(ctx, f.prefillRequestId())
+	if err != nil {
+		api.LogErrorf("[TraceID: %s] delete prefill request failed: %v", f.traceId, err)
+		return
+	}
+
+	f.isPrefillDeleted = true
+	api.LogDebugf("[TraceID: %s] delete prefill request", f.traceId)
+}
+
 func (f *Filter) deletePromptLength() {
 	if !f.isLoadAwareEnabled() || !f.isIncreaseRecorded || f.isPromptLengthDeleted {
 		return
@@ -482,7 +1444,14 @@ func (f *Filter) deletePromptLength() {
 		f.promptDecreaseTimer = nil
 	}
 
-	ctx := context.WithValue(context.Background(), metadata.CtxKeyTraceId, f.traceId)
+	// PD 分离模式下首 Token 到达即 Prefill 完成
+	if f.prefillHost != nil {
+		f.deletePrefillRequest()
+		f.isPromptLengthDeleted = true
+		return
+	}
+
+	ctx := f.metadataContext()
 	client := metadata.GetClientOrNoop()
 	err := client.DeleteRequestPrompt(ctx, f.UniqueId())
 	if err != nil {
@@ -504,7 +1473,9 @@ func (f *Filter) decreaseRequest() {
 		f.promptDecreaseTimer = nil
 	}
 
-	ctx := context.WithValue(context.Background(), metadata.CtxKeyTraceId, f.traceId)
+	f.deletePrefillRequest()
+
+	ctx := f.metadataContext()
 	client := metadata.GetClientOrNoop()
 	err := client.DeleteRequest(ctx, f.UniqueId())
 	if err != nil {
@@ -518,14 +1489,20 @@ func (f *Filter) saveKVCache() {
 		return
 	}
 
-	ctx := context.WithValue(context.Background(), metadata.CtxKeyTraceId, f.traceId)
+	// PD 分离模式下 Prompt 的 KV-Cache 由 Prefill 主机计算
+	ip := f.serverIp
+	if f.prefillHost != nil {
+		ip = f.prefillHost.Ip()
+	}
+
+	ctx := f.metadataContext()
 	client := metadata.GetClientOrNoop()
-	err := client.SaveKVCache(ctx, f.cluster, f.serverIp, f.promptHash)
+	err := client.SaveKVCache(ctx, f.cluster, ip, f.promptHash)
 	if err != nil {
 		api.LogErrorf("[TraceID: %s] save kv cache failed: %v", f.traceId, err)
 	}
 	api.LogDebugf("[TraceID: %s] save kv cache: cluster=%s, ip=%s",
-		f.traceId, f.cluster, f.serverIp)
+		f.traceId, f.cluster, ip)
 }
 
 // 辅助函数
@@ -541,11 +1518,3 @@ func getTraceID(headers api.RequestHeaderMap) string {
 	// 生成新的 Trace ID
 	return uuid.New().String()
 }
-
-func estimateTTFT(modelName string, promptLength int) int {
-	// 简单的 TTFT 估算
-	// 实际实现可以基于历史数据进行更精确的估算
-	baseTTFT := 100 // ms
-	lengthFactor := promptLength / 1000
-	return baseTTFT + lengthFactor*50
-}
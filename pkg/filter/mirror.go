@@ -0,0 +1,70 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"strings"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/mirror"
+)
+
+// mirrorSkipHeaders 不复制到镜像请求的请求头
+var mirrorSkipHeaders = map[string]bool{
+	"content-length":  true,
+	"host":            true,
+	"x-upstream-host": true,
+}
+
+// mirrorRequest 按采样比例将请求异步复制到影子集群
+func (f *Filter) mirrorRequest() {
+	mirrorConfig := f.config.FindMirror(f.modelKey)
+	if !mirrorConfig.Sample() {
+		return
+	}
+
+	host, err := f.randomHost(mirrorConfig.Cluster)
+	if err != nil {
+		api.LogDebugf("[TraceID: %s] skip mirror: %v", f.traceId, err)
+		return
+	}
+
+	headers := make(map[string]string)
+	f.reqHeaders.Range(func(key, value string) bool {
+		if !strings.HasPrefix(key, ":") && !mirrorSkipHeaders[key] {
+			headers[key] = value
+		}
+		return true
+	})
+
+	modelName := mirrorConfig.Model
+	if modelName == "" {
+		modelName = f.modelName
+	}
+
+	// 请求体在转码前复制，影子请求单独改写
+	mirror.GetSender().Send(&mirror.Request{
+		TraceId:   f.traceId,
+		Model:     f.modelKey,
+		Cluster:   mirrorConfig.Cluster,
+		Host:      host,
+		Path:      f.reqHeaders.Path(),
+		Headers:   headers,
+		Body:      append([]byte(nil), f.reqBuffer.Bytes()...),
+		ModelName: modelName,
+		Timeout:   mirrorConfig.GetTimeout(),
+	})
+}
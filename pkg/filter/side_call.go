@@ -0,0 +1,51 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// randomHost 为网关发起的旁路请求（分类模型、链式处理步骤、请求镜像）随机选择主机
+// 旁路请求不查询 Metadata-Center，避免增加主请求的延迟
+func (f *Filter) randomHost(cluster string) (types.Host, error) {
+	hosts := f.getClusterHosts(cluster)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts in cluster %s", cluster)
+	}
+	return hosts[rand.Intn(len(hosts))], nil
+}
+
+// sideCallTarget 按模型的路由规则为旁路请求选择主机，返回主机和请求体中使用的模型名
+// 规则配置了 LoRA 适配器时使用适配器名作为模型名
+func (f *Filter) sideCallTarget(model string) (types.Host, string, error) {
+	reqData, err := f.transcoder.RemapRequest(f.reqHeaders, model, f.claims)
+	if err != nil {
+		return nil, "", err
+	}
+	host, err := f.randomHost(reqData.Cluster)
+	if err != nil {
+		return nil, "", err
+	}
+
+	modelName := reqData.ModelName
+	if reqData.LbOptions != nil && reqData.LbOptions.GetLoraID() != "" {
+		modelName = reqData.LbOptions.GetLoraID()
+	}
+	return host, modelName, nil
+}
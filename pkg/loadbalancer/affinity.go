@@ -0,0 +1,160 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancer
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/lora"
+	"github.com/istio-llm-filter/pkg/ttlcache"
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+const (
+	// EnvAffinityMaxSessions 会话亲和记录的最大会话数
+	EnvAffinityMaxSessions = "SESSION_AFFINITY_MAX_SESSIONS"
+	// DefaultAffinityMaxSessions 默认会话亲和记录的最大会话数
+	DefaultAffinityMaxSessions = 100000
+)
+
+var (
+	globalAffinity     *AffinityStore
+	globalAffinityOnce sync.Once
+)
+
+// AffinityStore 会话亲和记录
+// 按 集群/会话标识 记录上一次选中的主机地址，过期或超过最大会话数时淘汰最久未访问的记录
+type AffinityStore struct {
+	entries *ttlcache.Cache[string]
+}
+
+// GetAffinityStore 获取全局会话亲和记录
+func GetAffinityStore() *AffinityStore {
+	globalAffinityOnce.Do(func() {
+		globalAffinity = NewAffinityStore(affinityMaxSessions())
+	})
+	return globalAffinity
+}
+
+// NewAffinityStore 创建会话亲和记录
+func NewAffinityStore(maxSessions int) *AffinityStore {
+	return &AffinityStore{
+		entries: ttlcache.New[string](maxSessions),
+	}
+}
+
+// Get 获取会话上一次选中的主机地址
+func (s *AffinityStore) Get(cluster, session string) (string, bool) {
+	return s.entries.Get(cluster + "/" + session)
+}
+
+// Set 记录会话选中的主机地址
+func (s *AffinityStore) Set(cluster, session, address string, ttl time.Duration) {
+	s.entries.Set(cluster+"/"+session, address, ttl)
+}
+
+// affinityAddress 获取会话上一次选中的主机地址，没有会话标识或记录时返回空
+func affinityAddress(ctx context.Context, clusterName string) string {
+	session := types.GetValueFromCtx(ctx, types.KeyAffinityKey, "")
+	if session == "" {
+		return ""
+	}
+	address, _ := GetAffinityStore().Get(clusterName, session)
+	return address
+}
+
+// chooseAffinityHost 从已经过过滤插件和 SLO 过滤的主机中选择会话上一次选中的主机
+// 主机不在列表中（已下线、不健康或被过滤）、未加载请求的 LoRA 适配器或未完成请求数达到上限时返回 nil，按正常评分选择
+func chooseAffinityHost(ctx context.Context, address string, stats []*EndpointStatsWrapper) *EndpointStatsWrapper {
+	if address == "" {
+		return nil
+	}
+	idx := slices.IndexFunc(stats, func(s *EndpointStatsWrapper) bool { return s.Host.Address() == address })
+	if idx < 0 {
+		return nil
+	}
+	stat := stats[idx]
+	if stat.EndpointStats == nil {
+		return nil
+	}
+	if loraID := types.GetValueFromCtx(ctx, types.KeyLoraID, ""); loraID != "" && stat.LoraLoaded == 0 {
+		return nil
+	}
+
+	// 检查亲和主机的负载
+	maxDepth := types.GetValueFromCtx(ctx, types.KeyAffinityMaxQueueDepth, types.DefaultAffinityMaxQueueDepth)
+	if stat.EndpointStats.TotalReqs >= maxDepth {
+		api.LogDebugf("affinity host %s over load ceiling, queue_depth=%d, max=%d", address, stat.EndpointStats.TotalReqs, maxDepth)
+		return nil
+	}
+
+	markAffinity(ctx)
+	return stat
+}
+
+// chooseAffinityHostWithoutStats 未启用负载感知或负载统计不可用时，从候选主机中选择会话上一次选中的主机
+// 没有负载统计时无法检查负载上限，只检查主机仍在候选列表中且已加载请求的 LoRA 适配器
+func chooseAffinityHostWithoutStats(ctx context.Context, address string, hosts []types.Host) types.Host {
+	if address == "" {
+		return nil
+	}
+	idx := slices.IndexFunc(hosts, func(h types.Host) bool { return h.Address() == address })
+	if idx < 0 {
+		return nil
+	}
+	host := hosts[idx]
+	if loraID := types.GetValueFromCtx(ctx, types.KeyLoraID, ""); loraID != "" && !lora.GetRegistry().Has(host.Ip(), loraID) {
+		return nil
+	}
+
+	markAffinity(ctx)
+	return host
+}
+
+// markAffinity 在 HostMatchInfo 中标记按会话亲和选中
+func markAffinity(ctx context.Context) {
+	if info := types.GetValueFromCtx[*types.HostMatchInfo](ctx, types.KeyHostMatchInfo, nil); info != nil {
+		info.Affinity = true
+	}
+}
+
+// recordAffinity 记录会话选中的主机
+func recordAffinity(ctx context.Context, clusterName string, host types.Host) {
+	session := types.GetValueFromCtx(ctx, types.KeyAffinityKey, "")
+	if session == "" || host == nil {
+		return
+	}
+	ttl := types.GetValueFromCtx[time.Duration](ctx, types.KeyAffinityTTL, 0)
+	if ttl <= 0 {
+		return
+	}
+	GetAffinityStore().Set(clusterName, session, host.Address(), ttl)
+}
+
+func affinityMaxSessions() int {
+	if v := os.Getenv(EnvAffinityMaxSessions); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultAffinityMaxSessions
+}
@@ -0,0 +1,108 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// discardLog 测试中丢弃 Envoy 日志
+type discardLog struct{}
+
+func (discardLog) Log(level api.LogType, message string) {}
+
+func (discardLog) LogLevel() api.LogType { return api.Critical }
+
+type testHost struct {
+	ip     string
+	labels map[string]string
+}
+
+func (h *testHost) Ip() string                { return h.ip }
+func (h *testHost) Port() uint32              { return 8000 }
+func (h *testHost) Address() string           { return fmt.Sprintf("%s:%d", h.ip, h.Port()) }
+func (h *testHost) Labels() map[string]string { return h.labels }
+
+func TestMain(m *testing.M) {
+	api.SetCommonCAPI(discardLog{})
+	os.Exit(m.Run())
+}
+
+func TestChooseHostAffinityWithoutStats(t *testing.T) {
+	hosts := []types.Host{
+		&testHost{ip: "10.0.0.1", labels: map[string]string{"subset": "a"}},
+		&testHost{ip: "10.0.0.2", labels: map[string]string{"subset": "a"}},
+		&testHost{ip: "10.0.0.3", labels: map[string]string{"subset": "b"}},
+	}
+
+	tests := []struct {
+		name string
+		// loadAware 为 true 时启用负载感知，测试中 Metadata-Center 未配置，负载统计不可用
+		loadAware bool
+		session   string
+		recorded  string
+		selector  map[string]string
+		want      string
+	}{
+		{name: "stats unavailable", loadAware: true, session: "s1", recorded: "10.0.0.2:8000", want: "10.0.0.2:8000"},
+		{name: "load aware disabled", session: "s1", recorded: "10.0.0.3:8000", want: "10.0.0.3:8000"},
+		{name: "affinity host filtered by selector", loadAware: true, session: "s1", recorded: "10.0.0.3:8000", selector: map[string]string{"subset": "a"}},
+		{name: "affinity host gone", session: "s1", recorded: "10.0.0.9:8000"},
+		{name: "no session", loadAware: true, recorded: "10.0.0.1:8000"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := fmt.Sprintf("cluster-%d", i)
+			GetAffinityStore().Set(cluster, tt.session, tt.recorded, time.Minute)
+
+			lb := InferenceLoadBalancerFactory(context.Background(), hosts)
+			// 多次选择，避免随机选择恰好选中亲和主机
+			for n := 0; n < 20; n++ {
+				info := &types.HostMatchInfo{}
+				ctx := context.WithValue(context.Background(), types.KeyClusterName, cluster)
+				ctx = context.WithValue(ctx, types.KeyLoadAwareEnable, tt.loadAware)
+				ctx = context.WithValue(ctx, types.KeyAffinityKey, tt.session)
+				ctx = context.WithValue(ctx, types.KeyHostMatchInfo, info)
+				ctx = context.WithValue(ctx, types.KeyLbSelector, tt.selector)
+
+				host := lb.ChooseHost(ctx)
+				if host == nil {
+					t.Fatal("ChooseHost() = nil")
+				}
+				if tt.want != "" {
+					if host.Address() != tt.want || !info.Affinity {
+						t.Fatalf("ChooseHost() = %s, affinity %v, want affinity host %s", host.Address(), info.Affinity, tt.want)
+					}
+					continue
+				}
+				if info.Affinity {
+					t.Fatalf("ChooseHost() = %s marked affinity", host.Address())
+				}
+				if v, ok := tt.selector["subset"]; ok && host.Labels()["subset"] != v {
+					t.Fatalf("ChooseHost() = %s outside selector %v", host.Address(), tt.selector)
+				}
+			}
+		})
+	}
+}
@@ -21,10 +21,13 @@ import (
 	"math"
 	"math/rand"
 	"slices"
+	"time"
 
 	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
 
+	"github.com/istio-llm-filter/pkg/lora"
 	"github.com/istio-llm-filter/pkg/metadata"
+	"github.com/istio-llm-filter/pkg/predictor"
 	"github.com/istio-llm-filter/pkg/types"
 )
 
@@ -50,10 +53,11 @@ func InferenceLoadBalancerFactory(ctx context.Context, hosts []types.Host) types
 // 算法流程：
 // 1. 根据 Label Selector 过滤主机
 // 2. 如果启用负载感知，查询 Metadata-Center 获取负载统计
-// 3. 如果启用缓存感知，查询 KV-Cache 获取缓存命中信息
-// 4. 计算每个主机的综合评分
-// 5. 选择 Top N% 候选集
-// 6. 从候选集中随机选择一个主机
+// 3. 执行过滤插件排除不合适的主机
+// 4. 如果启用缓存感知，查询 KV-Cache 获取缓存命中信息
+// 5. 执行评分插件计算每个主机的综合评分
+// 6. 选择 Top N% 候选集
+// 7. 从候选集中随机选择一个主机
 func (lb *InferenceLoadBalancer) ChooseHost(ctx context.Context) types.Host {
 	candidateHosts := lb.hosts
 
@@ -72,26 +76,76 @@ func (lb *InferenceLoadBalancer) ChooseHost(ctx context.Context) types.Host {
 	clusterName := types.MustGetValueFromCtx[string](ctx, types.KeyClusterName)
 	traceId := types.GetValueFromCtx(ctx, types.KeyTraceId, "")
 
-	// 2. 如果启用负载感知，使用多维度评分选择
+	// 2. 按需刷新 LoRA 适配器加载信息
+	loraID := types.GetValueFromCtx(ctx, types.KeyLoraID, "")
+	if loraID != "" {
+		refreshLoraAdapters(ctx, candidateHosts)
+	}
+
+	// 3. 查找会话上一次选中的主机，不依赖负载统计
+	affinity := affinityAddress(ctx, clusterName)
+
+	// 4. 如果启用负载感知，使用多维度评分选择
 	if isLoadAwareEnabled(ctx) {
+		ranked, err := lb.rankHosts(ctx, clusterName, candidateHosts)
+		if err != nil {
+			api.LogErrorf("failed to get endpoint stats for cluster %s: %v", clusterName, err)
+			if sloEnabled(ctx) {
+				api.LogWarnf("[TraceID: %s] skip slo for cluster %s: endpoint stats unavailable", traceId, clusterName)
+			}
+			if host := chooseAffinityHostWithoutStats(ctx, affinity, candidateHosts); host != nil {
+				api.LogInfof("[TraceID: %s] affinity host %s for cluster %s without stats", traceId, host.Address(), clusterName)
+				return host
+			}
+			return chooseFromCandidates(candidateHosts, clusterName, traceId)
+		}
+
+		// 会话亲和：会话上一次选中的主机通过过滤插件和 SLO 过滤时优先选择
+		if stat := chooseAffinityHost(ctx, affinity, ranked); stat != nil {
+			api.LogInfof("[TraceID: %s] affinity host %s for cluster %s", traceId, stat.Host.Address(), clusterName)
+			recordHostMatchInfo(ctx, ranked, stat.Host)
+			return stat.Host
+		}
+
 		candNum := candidateNumFromContext(ctx, candidateHosts)
-		hosts := lb.GetCandidateByStats(ctx, clusterName, candidateHosts, candNum)
-		return chooseFromCandidates(hosts, clusterName, traceId)
+		logCandidates(ctx, ranked, clusterName, candNum+5)
+		candidates := selectCandidates(ctx, ranked, candNum)
+		host := chooseByStrategy(ctx, candidates, clusterName, traceId)
+		recordHostMatchInfo(ctx, candidates, host)
+		return host
 	}
 
-	// 否则随机选择
+	// 否则按 LoRA 适配器过滤后，优先选择亲和主机，再优先在已加载适配器的主机中随机选择
+	if loraID != "" {
+		maxAdapters := types.GetValueFromCtx(ctx, types.KeyMaxAdaptersPerHost, types.DefaultMaxAdaptersPerHost)
+		candidateHosts = filterHostsByLora(candidateHosts, loraID, maxAdapters)
+	}
+	if host := chooseAffinityHostWithoutStats(ctx, affinity, candidateHosts); host != nil {
+		api.LogInfof("[TraceID: %s] affinity host %s for cluster %s", traceId, host.Address(), clusterName)
+		return host
+	}
+	if loraID != "" {
+		if loaded := hostsWithLora(candidateHosts, loraID); len(loaded) > 0 {
+			return chooseFromCandidates(loaded, clusterName, traceId)
+		}
+	}
 	return chooseFromCandidates(candidateHosts, clusterName, traceId)
 }
 
-// GetCandidateByStats 根据负载统计获取候选主机列表
-func (lb *InferenceLoadBalancer) GetCandidateByStats(ctx context.Context, clusterName string, hosts []types.Host, candNum int) []types.Host {
+// rankHosts 获取负载统计，执行过滤插件和评分插件，返回按评分降序排列并经过 SLO 过滤的主机列表
+func (lb *InferenceLoadBalancer) rankHosts(ctx context.Context, clusterName string, hosts []types.Host) ([]*EndpointStatsWrapper, error) {
 	// 获取负载统计
 	stats, err := getEndpointStats(ctx, clusterName, hosts)
 	if err != nil {
-		api.LogErrorf("failed to get endpoint stats for cluster %s: %v", clusterName, err)
-		return hosts
+		return nil, err
 	}
 
+	// 检查主机是否全部饱和
+	markSaturation(ctx, stats)
+
+	// 执行过滤插件
+	stats = runFilters(ctx, stats)
+
 	// 获取缓存统计
 	var cacheStats map[string]*EndpointCacheStats
 	if isCacheAwareEnabled(ctx) {
@@ -107,21 +161,132 @@ func (lb *InferenceLoadBalancer) GetCandidateByStats(ctx context.Context, cluste
 	// 按评分降序排序
 	slices.SortFunc(stats, compareByScore)
 
-	// 记录候选信息
+	// 优先选择满足 SLO 的主机
+	return filterBySLO(ctx, stats), nil
+}
+
+// logCandidates 记录评分最高的 limit 个主机
+func logCandidates(ctx context.Context, stats []*EndpointStatsWrapper, clusterName string, limit int) {
 	traceId := types.GetValueFromCtx(ctx, types.KeyTraceId, "")
-	for i, stat := range stats {
-		if i < candNum+5 {
-			api.LogInfof("[TraceID: %s] candidate %d for cluster %s: %s", traceId, i, clusterName, stat)
+	for i, stat := range stats[:min(limit, len(stats))] {
+		api.LogInfof("[TraceID: %s] candidate %d for cluster %s: %s", traceId, i, clusterName, stat)
+	}
+}
+
+// markSaturation 所有主机的未完成请求数都达到阈值时，在 HostMatchInfo 中标记饱和或过载
+func markSaturation(ctx context.Context, stats []*EndpointStatsWrapper) {
+	info := types.GetValueFromCtx[*types.HostMatchInfo](ctx, types.KeyHostMatchInfo, nil)
+	if info == nil {
+		return
+	}
+	info.Saturated = allHostsReach(stats, types.GetValueFromCtx(ctx, types.KeyHostQueueDepth, 0))
+	info.Overloaded = allHostsReach(stats, types.GetValueFromCtx(ctx, types.KeyShedQueueDepth, 0))
+}
+
+// allHostsReach 判断所有主机的未完成请求数是否都达到阈值
+func allHostsReach(stats []*EndpointStatsWrapper, threshold int) bool {
+	if threshold <= 0 || len(stats) == 0 {
+		return false
+	}
+	for _, stat := range stats {
+		if stat.EndpointStats == nil || stat.EndpointStats.TotalReqs < threshold {
+			return false
+		}
+	}
+	return true
+}
+
+// selectCandidates 根据选择策略从已排序的主机中截取候选集
+func selectCandidates(ctx context.Context, stats []*EndpointStatsWrapper, candNum int) []*EndpointStatsWrapper {
+	if len(stats) == 0 {
+		return stats
+	}
+
+	switch types.GetValueFromCtx(ctx, types.KeySelectStrategy, types.DefaultSelectStrategy) {
+	case types.SelectSoftmax:
+		// softmax 按评分加权，低分主机的概率自然很小，不再截取候选集
+		return stats
+	case types.SelectScoreGap:
+		// 与最高分差距不超过阈值的主机都进入候选集，且不少于最小候选数量
+		gap := types.GetValueFromCtx(ctx, types.KeyScoreGap, types.DefaultScoreGap)
+		minCand := types.GetValueFromCtx(ctx, types.KeyMinCandidates, types.DefaultMinCandidates)
+		best := stats[0].Score
+		n := 0
+		for n < len(stats) && (best-stats[n].Score <= gap || n < minCand) {
+			n++
 		}
+		return stats[:max(n, 1)]
+	default:
+		return stats[:min(candNum, len(stats))]
+	}
+}
+
+// recordHostMatchInfo 将选中主机的统计信息写回 context 中的 HostMatchInfo
+func recordHostMatchInfo(ctx context.Context, candidates []*EndpointStatsWrapper, host types.Host) {
+	info := types.GetValueFromCtx[*types.HostMatchInfo](ctx, types.KeyHostMatchInfo, nil)
+	if info == nil || host == nil {
+		return
+	}
+	for _, c := range candidates {
+		if c.Host != host {
+			continue
+		}
+		info.CacheRatio = c.CacheHitRate
+		info.PredictedTTFTMs = c.PredictedTTFT
+		if c.EndpointStats != nil {
+			info.QueueDepth = c.EndpointStats.TotalReqs
+		}
+		return
+	}
+}
+
+// chooseByStrategy 根据选择策略从候选集中选择一个主机
+func chooseByStrategy(ctx context.Context, candidates []*EndpointStatsWrapper, clusterName, traceId string) types.Host {
+	if types.GetValueFromCtx(ctx, types.KeySelectStrategy, types.DefaultSelectStrategy) == types.SelectSoftmax {
+		temperature := types.GetValueFromCtx(ctx, types.KeySoftmaxTemperature, types.DefaultSoftmaxTemperature)
+		return chooseBySoftmax(candidates, temperature, clusterName, traceId)
+	}
+
+	hosts := make([]types.Host, 0, len(candidates))
+	for _, c := range candidates {
+		hosts = append(hosts, c.Host)
+	}
+	return chooseFromCandidates(hosts, clusterName, traceId)
+}
+
+// chooseBySoftmax 按评分的 softmax 概率从候选集中选择一个主机
+// P(i) = exp((score_i - max) / T) / Σ exp((score_j - max) / T)，温度越低越集中于高分主机
+func chooseBySoftmax(candidates []*EndpointStatsWrapper, temperature float64, clusterName, traceId string) types.Host {
+	if len(candidates) == 0 {
+		return nil
+	}
+	if temperature <= 0 {
+		temperature = types.DefaultSoftmaxTemperature
 	}
 
-	// 返回 Top N 候选
-	result := make([]types.Host, 0, candNum)
-	for i := 0; i < candNum && i < len(stats); i++ {
-		result = append(result, stats[i].Host)
+	// 候选集已按评分降序排列，第一个为最高分
+	best := candidates[0].Score
+	weights := make([]float64, len(candidates))
+	var total float64
+	for i, c := range candidates {
+		weights[i] = math.Exp((c.Score - best) / temperature)
+		total += weights[i]
+	}
+
+	r := rand.Float64() * total
+	i := 0
+	for ; i < len(candidates)-1; i++ {
+		r -= weights[i]
+		if r < 0 {
+			break
+		}
 	}
+	host := candidates[i].Host
 
-	return result
+	api.LogInfof("[TraceID: %s] chose host %d/%d by softmax (p=%.3f): %s for cluster %s",
+		traceId, i+1, len(candidates), weights[i]/total, host.Address(), clusterName)
+
+	return host
 }
 
 // EndpointStatsWrapper 端点统计包装器
@@ -134,6 +299,12 @@ type EndpointStatsWrapper struct {
 	RequestLoad  float64
 	PrefillLoad  float64
 	CacheHitRate float64
+	KVUsage      float64
+	LoraLoaded   float64
+	TTFTLoad     float64
+
+	// 预测的 TTFT（毫秒）
+	PredictedTTFT float64
 
 	// 综合评分
 	Score float64
@@ -141,8 +312,8 @@ type EndpointStatsWrapper struct {
 
 // String 返回统计信息的字符串表示
 func (s *EndpointStatsWrapper) String() string {
-	return fmt.Sprintf("host=%s, score=%.3f, reqLoad=%.3f, prefillLoad=%.3f, cacheHit=%.3f, totalReqs=%d, promptLen=%d",
-		s.Host.Ip(), s.Score, s.RequestLoad, s.PrefillLoad, s.CacheHitRate,
+	return fmt.Sprintf("host=%s, score=%.3f, reqLoad=%.3f, prefillLoad=%.3f, cacheHit=%.3f, kvUsage=%.3f, lora=%.0f, ttft=%.0fms, totalReqs=%d, promptLen=%d",
+		s.Host.Ip(), s.Score, s.RequestLoad, s.PrefillLoad, s.CacheHitRate, s.KVUsage, s.LoraLoaded, s.PredictedTTFT,
 		s.EndpointStats.TotalReqs, s.EndpointStats.PromptLength)
 }
 
@@ -179,6 +350,10 @@ func getEndpointStats(ctx context.Context, clusterName string, hosts []types.Hos
 				Host:          host,
 				EndpointStats: stat,
 			}
+			// 同步 Metadata-Center 上报的 LoRA 适配器信息
+			if stat.LoraAdapters != nil {
+				lora.GetRegistry().Update(host.Ip(), stat.LoraAdapters)
+			}
 		}
 	}
 
@@ -213,7 +388,8 @@ func getCacheStats(ctx context.Context) (map[string]*EndpointCacheStats, error)
 }
 
 // mergeStatsAndScore 合并统计数据并计算评分
-// 评分公式: Score = W1 * CacheRatio - W2 * RequestLoad - W3 * PrefillLoad
+// 先计算各维度的归一化值，再由评分插件流水线计算综合评分
+// 默认流水线: Score = W1 * CacheRatio + W2 * (1 - RequestLoad) + W3 * (1 - PrefillLoad) + W4 * (1 - KVUsage) + W5 * LoraLoaded
 func mergeStatsAndScore(ctx context.Context, loadStats []*EndpointStatsWrapper, cacheStats map[string]*EndpointCacheStats) []*EndpointStatsWrapper {
 	// 计算负载范围
 	var maxQueueSize float64 = 0
@@ -235,19 +411,17 @@ func mergeStatsAndScore(ctx context.Context, loadStats []*EndpointStatsWrapper,
 		minQueueSize = 0
 	}
 
-	// 获取权重配置
-	cacheHitWeight := float64(types.GetValueFromCtx(ctx, types.KeyCacheRatioWeight, types.DefaultCacheRatioWeight))
-	prefillWeight := float64(types.GetValueFromCtx(ctx, types.KeyLoadPrefillWeight, types.DefaultPrefillLoadWeight))
-	configRequestWeight := float64(types.GetValueFromCtx(ctx, types.KeyLoadRequestWeight, types.DefaultRequestLoadWeight))
+	loraID := types.GetValueFromCtx(ctx, types.KeyLoraID, "")
+	modelName := types.GetValueFromCtx(ctx, types.KeyModelName, "")
+	promptLength := types.GetValueFromCtx(ctx, types.KeyPromptLength, 0)
+	ttftPredictor := predictor.GetTTFTPredictor()
+	var maxTTFT float64
 
-	// 动态调整请求负载权重：当并发差异大于 5 时，增加权重
+	// 请求负载归一化区间：当并发差异大于 5 时，默认流水线会放大请求负载权重
 	delta := math.Max(2, maxQueueSize-minQueueSize)
-	requestLoadWeight := configRequestWeight * math.Ceil(delta/5)
-
-	api.LogDebugf("scoring weights: cache=%.1f, request=%.1f, prefill=%.1f, delta=%.1f",
-		cacheHitWeight, requestLoadWeight, prefillWeight, delta)
+	requestWeightScale := math.Ceil(delta / 5)
 
-	// 计算每个端点的评分
+	// 计算每个端点的归一化统计值
 	for _, stat := range loadStats {
 		// 设置缓存命中率
 		stat.CacheHitRate = 0
@@ -258,9 +432,16 @@ func mergeStatsAndScore(ctx context.Context, loadStats []*EndpointStatsWrapper,
 			}
 		}
 
+		// 设置 LoRA 适配器是否已加载
+		stat.LoraLoaded = 0
+		if loraID != "" && lora.GetRegistry().Has(stat.Host.Ip(), loraID) {
+			stat.LoraLoaded = 1
+		}
+
 		// 计算归一化负载
 		stat.RequestLoad = 1.0
 		stat.PrefillLoad = 0.0
+		stat.KVUsage = 0.0
 
 		if stat.EndpointStats != nil {
 			// 请求负载归一化: (当前请求数 - 最小请求数) / delta
@@ -269,14 +450,34 @@ func mergeStatsAndScore(ctx context.Context, loadStats []*EndpointStatsWrapper,
 
 			// Prefill 负载归一化: 当前 Prompt 长度 / 最大 Prompt 长度
 			stat.PrefillLoad = float64(stat.EndpointStats.PromptLength) / float64(maxPromptLength)
+
+			// KV-Cache 使用率本身已归一化到 0-1
+			stat.KVUsage = clamp01(stat.EndpointStats.KVUsage)
 		}
 
-		// 计算综合评分
-		// Score = W1 * cache_ratio - W2 * request_load - W3 * prefill_load
-		// 缓存命中率越高越好（正向），请求负载和 Prefill 负载越低越好（负向）
-		stat.Score = cacheHitWeight*stat.CacheHitRate - requestLoadWeight*stat.RequestLoad - prefillWeight*stat.PrefillLoad
+		// 预测 TTFT
+		queueDepth := 0
+		if stat.EndpointStats != nil {
+			queueDepth = stat.EndpointStats.TotalReqs
+		}
+		ttft := ttftPredictor.Predict(modelName, stat.Host.Ip(), promptLength, queueDepth)
+		stat.PredictedTTFT = float64(ttft) / float64(time.Millisecond)
+		maxTTFT = math.Max(maxTTFT, stat.PredictedTTFT)
 	}
 
+	// 预测 TTFT 归一化: 预测 TTFT / 最大预测 TTFT
+	for _, stat := range loadStats {
+		stat.TTFTLoad = 0
+		if maxTTFT > 0 {
+			stat.TTFTLoad = stat.PredictedTTFT / maxTTFT
+		}
+	}
+
+	api.LogDebugf("scoring: delta=%.1f, request weight scale=%.1f", delta, requestWeightScale)
+
+	// 执行评分插件
+	runScorers(ctx, loadStats, requestWeightScale)
+
 	return loadStats
 }
 
@@ -304,6 +505,49 @@ func filterHostsBySelector(hosts []types.Host, selector map[string]string) []typ
 	return matched
 }
 
+// refreshLoraAdapters 按需刷新主机的 LoRA 适配器加载信息，未配置轮询间隔时只依赖 Metadata-Center 上报
+func refreshLoraAdapters(ctx context.Context, hosts []types.Host) {
+	registry := lora.GetRegistry()
+	interval := types.GetValueFromCtx[time.Duration](ctx, types.KeyLoraPollInterval, 0)
+	for _, host := range hosts {
+		registry.RefreshIfStale(host, interval)
+	}
+}
+
+// filterHostsByLora 根据 LoRA 适配器加载情况过滤主机
+// 未加载该适配器且已加载适配器数量达到上限的主机会被排除，使适配器流量集中而不是频繁换入换出
+// 如果所有主机都被排除，则返回原列表
+func filterHostsByLora(hosts []types.Host, loraID string, maxAdapters int) []types.Host {
+	if maxAdapters <= 0 {
+		return hosts
+	}
+
+	registry := lora.GetRegistry()
+	matched := make([]types.Host, 0, len(hosts))
+	for _, host := range hosts {
+		if loraAdmissible(registry, host.Ip(), loraID, maxAdapters) {
+			matched = append(matched, host)
+		}
+	}
+	if len(matched) == 0 {
+		api.LogWarnf("all %d hosts reach max adapters %d for lora %s, skip filtering", len(hosts), maxAdapters, loraID)
+		return hosts
+	}
+	return matched
+}
+
+// hostsWithLora 返回已加载指定 LoRA 适配器的主机
+func hostsWithLora(hosts []types.Host, loraID string) []types.Host {
+	registry := lora.GetRegistry()
+	var matched []types.Host
+	for _, host := range hosts {
+		if registry.Has(host.Ip(), loraID) {
+			matched = append(matched, host)
+		}
+	}
+	return matched
+}
+
 // chooseFromCandidates 从候选主机中随机选择一个
 func chooseFromCandidates(hosts []types.Host, clusterName, traceId string) types.Host {
 	if len(hosts) == 0 {
@@ -322,10 +566,11 @@ func chooseFromCandidates(hosts []types.Host, clusterName, traceId string) types
 // candidateNumFromContext 从 context 获取候选数量
 func candidateNumFromContext(ctx context.Context, hosts []types.Host) int {
 	percent := types.GetValueFromCtx(ctx, types.KeyCandidatePercent, types.DefaultCandidatePercent)
-	// 至少 1 个，最多全部
+	minCand := types.GetValueFromCtx(ctx, types.KeyMinCandidates, types.DefaultMinCandidates)
+	// 至少 max(1, 最小候选数量) 个，最多全部
 	candNum := len(hosts) * percent / 100
-	if candNum < 1 {
-		candNum = 1
+	if candNum < max(minCand, 1) {
+		candNum = max(minCand, 1)
 	}
 	if candNum > len(hosts) {
 		candNum = len(hosts)
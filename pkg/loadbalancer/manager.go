@@ -63,6 +63,32 @@ func ChooseServer(ctx context.Context, cluster string, lbType types.LoadBalancer
 	if host == nil {
 		return nil, fmt.Errorf("failed to choose host from cluster %s", cluster)
 	}
+	recordAffinity(ctx, cluster, host)
 
 	return host, nil
 }
+
+// ChooseServerPair 从集群中选择 Prefill 和 Decode 主机对
+// 负载均衡器必须实现 types.PairLoadBalancer
+func ChooseServerPair(ctx context.Context, cluster string, lbType types.LoadBalancerType, hosts []types.Host) (types.Host, types.Host, error) {
+	if len(hosts) == 0 {
+		return nil, nil, fmt.Errorf("no available hosts in cluster %s", cluster)
+	}
+
+	// 设置集群名称到 context
+	ctx = context.WithValue(ctx, types.KeyClusterName, cluster)
+
+	// 创建负载均衡器
+	lb, ok := CreateLbByType(lbType, ctx, hosts).(types.PairLoadBalancer)
+	if !ok {
+		return nil, nil, fmt.Errorf("load balancer type %s does not support prefill/decode pairing", lbType)
+	}
+
+	// 选择主机对
+	prefill, decode := lb.ChoosePair(ctx)
+	if prefill == nil || decode == nil {
+		return nil, nil, fmt.Errorf("failed to choose prefill/decode hosts from cluster %s", cluster)
+	}
+
+	return prefill, decode, nil
+}
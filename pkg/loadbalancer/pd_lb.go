@@ -0,0 +1,111 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancer
+
+import (
+	"context"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// PD 分离部署的主机角色标签
+const (
+	// PDRoleLabel 主机角色标签键
+	PDRoleLabel = "role"
+	// PDRolePrefill Prefill 角色
+	PDRolePrefill = "prefill"
+	// PDRoleDecode Decode 角色
+	PDRoleDecode = "decode"
+)
+
+func init() {
+	// 注册 PD 分离负载均衡器
+	RegisterLbType(types.PDDisaggLB, PDLoadBalancerFactory)
+}
+
+// PDLoadBalancer Prefill/Decode 分离负载均衡器
+// 分别从 role=prefill 和 role=decode 的主机中各选择一个
+type PDLoadBalancer struct {
+	hosts []types.Host
+}
+
+// PDLoadBalancerFactory 创建 PD 分离负载均衡器
+func PDLoadBalancerFactory(ctx context.Context, hosts []types.Host) types.LoadBalancer {
+	return &PDLoadBalancer{
+		hosts: hosts,
+	}
+}
+
+// ChooseHost 选择 Decode 主机
+// 请求实际发往 Decode 主机，由引擎的分离代理再转发到 Prefill 主机
+func (lb *PDLoadBalancer) ChooseHost(ctx context.Context) types.Host {
+	_, decode := lb.ChoosePair(ctx)
+	return decode
+}
+
+// ChoosePair 选择 Prefill 和 Decode 主机对
+// Prefill 主机：缓存感知 + Prefill 负载感知评分
+// Decode 主机：仅按请求负载和 KV-Cache 使用率评分
+func (lb *PDLoadBalancer) ChoosePair(ctx context.Context) (types.Host, types.Host) {
+	// Prefill 和 Decode 主机分别选择，不使用会话亲和
+	ctx = context.WithValue(ctx, types.KeyAffinityKey, "")
+
+	prefillHosts := filterHostsBySelector(lb.hosts, map[string]string{PDRoleLabel: PDRolePrefill})
+	decodeHosts := filterHostsBySelector(lb.hosts, map[string]string{PDRoleLabel: PDRoleDecode})
+	if len(prefillHosts) == 0 || len(decodeHosts) == 0 {
+		api.LogWarnf("no prefill or decode hosts, prefill=%d, decode=%d", len(prefillHosts), len(decodeHosts))
+		return nil, nil
+	}
+
+	prefill := (&InferenceLoadBalancer{hosts: prefillHosts}).ChooseHost(ctx)
+	if prefill == nil {
+		return nil, nil
+	}
+
+	// Decode 阶段与 Prompt 缓存和 Prefill 负载无关
+	decodeCtx := context.WithValue(ctx, types.KeyCacheAwareEnable, false)
+	decodeCtx = context.WithValue(decodeCtx, types.KeyCacheRatioWeight, 0)
+	decodeCtx = context.WithValue(decodeCtx, types.KeyLoadPrefillWeight, 0)
+	if pipeline, legacy := pipelineFromContext(ctx); !legacy {
+		decodeCtx = context.WithValue(decodeCtx, types.KeyLbPipeline, decodePipeline(pipeline))
+	}
+	// 主机匹配信息（排队数、预测 TTFT）只记录 Prefill 主机
+	decodeCtx = context.WithValue(decodeCtx, types.KeyHostMatchInfo, (*types.HostMatchInfo)(nil))
+	decode := (&InferenceLoadBalancer{hosts: decodeHosts}).ChooseHost(decodeCtx)
+	if decode == nil {
+		return nil, nil
+	}
+
+	return prefill, decode
+}
+
+// decodeScorerExcluded Decode 主机评分时去掉的评分插件
+var decodeScorerExcluded = map[string]bool{
+	ScorerCacheHit:    true,
+	ScorerPrefillLoad: true,
+}
+
+// decodePipeline 复制配置的插件流水线并去掉缓存命中和 Prefill 负载评分插件
+func decodePipeline(pipeline *types.LbPipelineConfig) *types.LbPipelineConfig {
+	decode := &types.LbPipelineConfig{Filters: pipeline.Filters}
+	for _, p := range pipeline.Scorers {
+		if !decodeScorerExcluded[p.Name] {
+			decode.Scorers = append(decode.Scorers, p)
+		}
+	}
+	return decode
+}
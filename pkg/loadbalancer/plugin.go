@@ -0,0 +1,247 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/lora"
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// 内置插件名称
+const (
+	// FilterKVUsage 排除 KV-Cache 使用率超过阈值的主机
+	FilterKVUsage = "kv_usage"
+	// FilterLora 排除无法再加载所需 LoRA 适配器的主机
+	FilterLora = "lora"
+
+	// ScorerCacheHit 缓存命中率评分
+	ScorerCacheHit = "cache_hit"
+	// ScorerRequestLoad 请求负载评分
+	ScorerRequestLoad = "request_load"
+	// ScorerPrefillLoad Prefill 负载评分
+	ScorerPrefillLoad = "prefill_load"
+	// ScorerKVUsage KV-Cache 使用率评分
+	ScorerKVUsage = "kv_usage"
+	// ScorerLoraAffinity LoRA 适配器亲和评分
+	ScorerLoraAffinity = "lora_affinity"
+	// ScorerTTFT 预测 TTFT 评分
+	ScorerTTFT = "ttft"
+)
+
+// Filter 主机过滤插件
+type Filter interface {
+	// Filter 返回保留的主机
+	Filter(ctx context.Context, stats []*EndpointStatsWrapper) []*EndpointStatsWrapper
+}
+
+// FilterFunc 函数形式的主机过滤插件
+type FilterFunc func(ctx context.Context, stats []*EndpointStatsWrapper) []*EndpointStatsWrapper
+
+// Filter 实现 Filter 接口
+func (f FilterFunc) Filter(ctx context.Context, stats []*EndpointStatsWrapper) []*EndpointStatsWrapper {
+	return f(ctx, stats)
+}
+
+// Scorer 主机评分插件
+// 评分在归一化统计值计算完成后调用
+type Scorer interface {
+	// Score 返回主机的归一化评分 (0-1)，越大越好
+	Score(ctx context.Context, stat *EndpointStatsWrapper) float64
+}
+
+// ScorerFunc 函数形式的主机评分插件
+type ScorerFunc func(ctx context.Context, stat *EndpointStatsWrapper) float64
+
+// Score 实现 Scorer 接口
+func (f ScorerFunc) Score(ctx context.Context, stat *EndpointStatsWrapper) float64 {
+	return f(ctx, stat)
+}
+
+// 插件注册表
+var (
+	filterPlugins = make(map[string]Filter)
+	scorerPlugins = make(map[string]Scorer)
+)
+
+func init() {
+	RegisterFilter(FilterKVUsage, FilterFunc(filterByKVUsage))
+	RegisterFilter(FilterLora, FilterFunc(filterByLora))
+
+	RegisterScorer(ScorerCacheHit, ScorerFunc(func(ctx context.Context, stat *EndpointStatsWrapper) float64 {
+		return stat.CacheHitRate
+	}))
+	RegisterScorer(ScorerRequestLoad, ScorerFunc(func(ctx context.Context, stat *EndpointStatsWrapper) float64 {
+		return 1 - stat.RequestLoad
+	}))
+	RegisterScorer(ScorerPrefillLoad, ScorerFunc(func(ctx context.Context, stat *EndpointStatsWrapper) float64 {
+		return 1 - stat.PrefillLoad
+	}))
+	RegisterScorer(ScorerKVUsage, ScorerFunc(func(ctx context.Context, stat *EndpointStatsWrapper) float64 {
+		return 1 - stat.KVUsage
+	}))
+	RegisterScorer(ScorerLoraAffinity, ScorerFunc(func(ctx context.Context, stat *EndpointStatsWrapper) float64 {
+		return stat.LoraLoaded
+	}))
+	RegisterScorer(ScorerTTFT, ScorerFunc(func(ctx context.Context, stat *EndpointStatsWrapper) float64 {
+		return 1 - stat.TTFTLoad
+	}))
+}
+
+// RegisterFilter 注册主机过滤插件
+func RegisterFilter(name string, filter Filter) {
+	filterPlugins[name] = filter
+}
+
+// RegisterScorer 注册主机评分插件
+func RegisterScorer(name string, scorer Scorer) {
+	scorerPlugins[name] = scorer
+}
+
+// ValidatePipeline 校验插件流水线中的插件均已注册
+func ValidatePipeline(pipeline *types.LbPipelineConfig) error {
+	if pipeline == nil {
+		return nil
+	}
+	for _, p := range pipeline.Filters {
+		if _, ok := filterPlugins[p.Name]; !ok {
+			return fmt.Errorf("unknown filter plugin %s", p.Name)
+		}
+	}
+	for _, p := range pipeline.Scorers {
+		if _, ok := scorerPlugins[p.Name]; !ok {
+			return fmt.Errorf("unknown scorer plugin %s", p.Name)
+		}
+		if p.Weight < 0 {
+			return fmt.Errorf("negative weight %.3f for scorer plugin %s", p.Weight, p.Name)
+		}
+	}
+	return nil
+}
+
+// runFilters 依次执行过滤插件
+// 某个插件排除了全部主机时忽略该插件的结果，避免无主机可用
+func runFilters(ctx context.Context, stats []*EndpointStatsWrapper) []*EndpointStatsWrapper {
+	pipeline, _ := pipelineFromContext(ctx)
+	for _, p := range pipeline.Filters {
+		filter, ok := filterPlugins[p.Name]
+		if !ok {
+			continue
+		}
+		result := filter.Filter(ctx, stats)
+		if len(result) == 0 {
+			api.LogWarnf("filter plugin %s excluded all %d hosts, skip it", p.Name, len(stats))
+			continue
+		}
+		stats = result
+	}
+	return stats
+}
+
+// runScorers 计算每个主机的综合评分
+// Score = Σ weight_i * score_i
+func runScorers(ctx context.Context, stats []*EndpointStatsWrapper, requestWeightScale float64) {
+	pipeline, legacy := pipelineFromContext(ctx)
+	for _, stat := range stats {
+		stat.Score = 0
+		for _, p := range pipeline.Scorers {
+			scorer, ok := scorerPlugins[p.Name]
+			if !ok {
+				continue
+			}
+			weight := p.Weight
+			// 兼容原有评分公式：请求负载权重随并发差异动态放大
+			if legacy && p.Name == ScorerRequestLoad {
+				weight *= requestWeightScale
+			}
+			stat.Score += weight * scorer.Score(ctx, stat)
+		}
+	}
+}
+
+// pipelineFromContext 获取插件流水线配置
+// 未配置时根据整数权重构建默认流水线，第二个返回值表示是否为默认流水线
+func pipelineFromContext(ctx context.Context) (*types.LbPipelineConfig, bool) {
+	if pipeline := types.GetValueFromCtx[*types.LbPipelineConfig](ctx, types.KeyLbPipeline, nil); pipeline != nil {
+		return pipeline, false
+	}
+
+	return &types.LbPipelineConfig{
+		Filters: []*types.LbPluginConfig{
+			{Name: FilterKVUsage},
+			{Name: FilterLora},
+		},
+		Scorers: []*types.LbPluginConfig{
+			{Name: ScorerCacheHit, Weight: float64(types.GetValueFromCtx(ctx, types.KeyCacheRatioWeight, types.DefaultCacheRatioWeight))},
+			{Name: ScorerRequestLoad, Weight: float64(types.GetValueFromCtx(ctx, types.KeyLoadRequestWeight, types.DefaultRequestLoadWeight))},
+			{Name: ScorerPrefillLoad, Weight: float64(types.GetValueFromCtx(ctx, types.KeyLoadPrefillWeight, types.DefaultPrefillLoadWeight))},
+			{Name: ScorerKVUsage, Weight: float64(types.GetValueFromCtx(ctx, types.KeyKVUsageWeight, types.DefaultKVUsageWeight))},
+			{Name: ScorerLoraAffinity, Weight: float64(types.GetValueFromCtx(ctx, types.KeyLoraAffinityWeight, types.DefaultLoraAffinityWeight))},
+		},
+	}, true
+}
+
+// filterByKVUsage 排除 KV-Cache 使用率超过阈值的主机
+func filterByKVUsage(ctx context.Context, stats []*EndpointStatsWrapper) []*EndpointStatsWrapper {
+	threshold := types.GetValueFromCtx(ctx, types.KeyKVUsageThreshold, types.DefaultKVUsageThreshold)
+	if threshold <= 0 {
+		return stats
+	}
+
+	limit := float64(threshold) / 100
+	result := make([]*EndpointStatsWrapper, 0, len(stats))
+	for _, stat := range stats {
+		if stat.EndpointStats != nil && stat.EndpointStats.KVUsage >= limit {
+			api.LogDebugf("exclude host %s by kv usage: %.3f >= %.3f",
+				stat.Host.Ip(), stat.EndpointStats.KVUsage, limit)
+			continue
+		}
+		result = append(result, stat)
+	}
+	return result
+}
+
+// filterByLora 排除未加载所需 LoRA 适配器且已达到适配器数量上限的主机
+func filterByLora(ctx context.Context, stats []*EndpointStatsWrapper) []*EndpointStatsWrapper {
+	loraID := types.GetValueFromCtx(ctx, types.KeyLoraID, "")
+	maxAdapters := types.GetValueFromCtx(ctx, types.KeyMaxAdaptersPerHost, types.DefaultMaxAdaptersPerHost)
+	if loraID == "" || maxAdapters <= 0 {
+		return stats
+	}
+
+	registry := lora.GetRegistry()
+	result := make([]*EndpointStatsWrapper, 0, len(stats))
+	for _, stat := range stats {
+		if loraAdmissible(registry, stat.Host.Ip(), loraID, maxAdapters) {
+			result = append(result, stat)
+		}
+	}
+	return result
+}
+
+// loraAdmissible 判断主机能否承接指定 LoRA 适配器的请求
+func loraAdmissible(registry *lora.Registry, ip, loraID string, maxAdapters int) bool {
+	return registry.Has(ip, loraID) || registry.Count(ip) < maxAdapters
+}
+
+// clamp01 将值限制在 0-1 之间
+func clamp01(v float64) float64 {
+	return math.Min(math.Max(v, 0), 1)
+}
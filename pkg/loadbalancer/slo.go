@@ -0,0 +1,66 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancer
+
+import (
+	"context"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/predictor"
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// sloEnabled 判断是否配置了延迟目标
+func sloEnabled(ctx context.Context) bool {
+	return types.GetValueFromCtx[time.Duration](ctx, types.KeySLOTTFT, 0) > 0 ||
+		types.GetValueFromCtx[time.Duration](ctx, types.KeySLOTPOT, 0) > 0
+}
+
+// filterBySLO 保留预测 TTFT 和 TPOT 满足 SLO 的主机
+// 没有主机满足 SLO 时保留全部主机，并在 HostMatchInfo 中标记 SLO 无法满足
+func filterBySLO(ctx context.Context, stats []*EndpointStatsWrapper) []*EndpointStatsWrapper {
+	if !sloEnabled(ctx) {
+		return stats
+	}
+	ttftSLO := types.GetValueFromCtx[time.Duration](ctx, types.KeySLOTTFT, 0)
+	tpotSLO := types.GetValueFromCtx[time.Duration](ctx, types.KeySLOTPOT, 0)
+
+	modelName := types.GetValueFromCtx(ctx, types.KeyModelName, "")
+	tpotPredictor := predictor.GetTPOTPredictor()
+
+	result := make([]*EndpointStatsWrapper, 0, len(stats))
+	for _, stat := range stats {
+		if ttftSLO > 0 && stat.PredictedTTFT > float64(ttftSLO)/float64(time.Millisecond) {
+			continue
+		}
+		if tpotSLO > 0 {
+			if tpot, ok := tpotPredictor.Predict(modelName, stat.Host.Ip()); ok && tpot > tpotSLO {
+				continue
+			}
+		}
+		result = append(result, stat)
+	}
+
+	if len(result) == 0 {
+		api.LogWarnf("no host meets slo for model %s, ttft=%s, tpot=%s", modelName, ttftSLO, tpotSLO)
+		if info := types.GetValueFromCtx[*types.HostMatchInfo](ctx, types.KeyHostMatchInfo, nil); info != nil {
+			info.SLOUnmet = true
+		}
+		return stats
+	}
+	return result
+}
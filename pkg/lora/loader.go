@@ -0,0 +1,177 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lora
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+const (
+	// LoadAdapterPath vLLM 动态加载 LoRA 适配器 API 路径
+	LoadAdapterPath = "/v1/load_lora_adapter"
+
+	// DefaultLoadTimeout 默认适配器加载超时时间
+	DefaultLoadTimeout = 30 * time.Second
+)
+
+// ErrLoadTimeout 适配器加载超时
+var ErrLoadTimeout = errors.New("lora adapter load timeout")
+
+var (
+	globalLoader     *Loader
+	globalLoaderOnce sync.Once
+)
+
+// loadCall 一次进行中的适配器加载
+type loadCall struct {
+	done chan struct{}
+	err  error
+}
+
+// Loader LoRA 适配器动态加载器
+// 同一主机上同一适配器的并发加载会被合并为一次请求，加载成功后记录到 Registry
+type Loader struct {
+	mu         sync.Mutex
+	calls      map[string]*loadCall
+	registry   *Registry
+	httpClient *http.Client
+}
+
+// LoadAdapterRequest 动态加载适配器请求
+type LoadAdapterRequest struct {
+	LoraName string `json:"lora_name"`
+	LoraPath string `json:"lora_path"`
+}
+
+// GetLoader 获取全局 LoRA 适配器加载器
+func GetLoader() *Loader {
+	globalLoaderOnce.Do(func() {
+		globalLoader = NewLoader(GetRegistry())
+	})
+	return globalLoader
+}
+
+// NewLoader 创建 LoRA 适配器加载器
+func NewLoader(registry *Registry) *Loader {
+	return &Loader{
+		calls:      make(map[string]*loadCall),
+		registry:   registry,
+		httpClient: &http.Client{},
+	}
+}
+
+// Ensure 确保主机已加载指定适配器
+// 已加载时直接返回；否则调用后端动态加载接口，超时返回 ErrLoadTimeout
+func (l *Loader) Ensure(ctx context.Context, host types.Host, loraName, loraPath string, timeout time.Duration) error {
+	if l.registry.Has(host.Ip(), loraName) {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultLoadTimeout
+	}
+
+	key := host.Address() + "/" + loraName
+	l.mu.Lock()
+	call, ok := l.calls[key]
+	if !ok {
+		call = &loadCall{done: make(chan struct{})}
+		l.calls[key] = call
+		go l.doLoad(key, call, host, loraName, loraPath, timeout)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// doLoad 执行一次适配器加载，完成后唤醒所有等待者
+func (l *Loader) doLoad(key string, call *loadCall, host types.Host, loraName, loraPath string, timeout time.Duration) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.calls, key)
+		l.mu.Unlock()
+		close(call.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	call.err = l.load(ctx, host, loraName, loraPath)
+	if errors.Is(call.err, context.DeadlineExceeded) {
+		call.err = fmt.Errorf("%w: host=%s, lora=%s, timeout=%s", ErrLoadTimeout, host.Address(), loraName, timeout)
+	}
+	if call.err != nil {
+		api.LogWarnf("load lora adapter %s on %s failed: %v", loraName, host.Address(), call.err)
+		return
+	}
+
+	l.registry.Add(host.Ip(), loraName)
+	api.LogInfof("load lora adapter %s on %s succeeded, cost=%s", loraName, host.Address(), time.Since(start))
+}
+
+// load 调用后端 /v1/load_lora_adapter 接口
+func (l *Loader) load(ctx context.Context, host types.Host, loraName, loraPath string) error {
+	body, err := json.Marshal(&LoadAdapterRequest{
+		LoraName: loraName,
+		LoraPath: loraPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	reqUrl := fmt.Sprintf("http://%s%s", host.Address(), LoadAdapterPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqUrl, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body failed: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	// 适配器已被其他网关实例加载
+	if resp.StatusCode == http.StatusBadRequest && bytes.Contains(respBody, []byte("already been loaded")) {
+		return nil
+	}
+	return fmt.Errorf("unexpected status code %v, body: %s", resp.StatusCode, string(respBody))
+}
@@ -0,0 +1,274 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lora 维护各后端主机已加载的 LoRA 适配器信息
+package lora
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// API 路径常量
+const (
+	// ModelsPath vLLM 模型列表 API 路径
+	ModelsPath = "/v1/models"
+)
+
+// 环境变量名称
+const (
+	EnvPollTimeout = "LORA_ADAPTER_POLL_TIMEOUT"
+)
+
+const (
+	// hostIdleTTL 主机超过该时间未被选择或更新时从注册表删除，用于清理已下线的主机
+	hostIdleTTL = 10 * time.Minute
+	// sweepInterval 清理已下线主机的间隔
+	sweepInterval = time.Minute
+	// maxConcurrentPolls 同时轮询 /v1/models 的最大主机数
+	maxConcurrentPolls = 16
+)
+
+var (
+	// 全局单例
+	globalRegistry     *Registry
+	globalRegistryOnce sync.Once
+)
+
+// hostAdapters 主机已加载的适配器
+type hostAdapters struct {
+	adapters  map[string]struct{}
+	updatedAt time.Time
+	// polledAt 最近一次发起轮询的时间，同一主机在一个轮询间隔内只轮询一次
+	polledAt time.Time
+	// seenAt 最近一次被选择或更新的时间
+	seenAt time.Time
+}
+
+// Registry LoRA 适配器注册表
+// 记录每个主机已加载的适配器，数据来源于 Metadata-Center 上报或 /v1/models 轮询
+// 超过 hostIdleTTL 未被选择或更新的主机视为已下线，定期删除
+type Registry struct {
+	mu        sync.RWMutex
+	hosts     map[string]*hostAdapters
+	polls     chan struct{}
+	lastSweep time.Time
+
+	httpClient  *http.Client
+	pollTimeout time.Duration
+}
+
+// GetRegistry 获取全局 LoRA 适配器注册表
+func GetRegistry() *Registry {
+	globalRegistryOnce.Do(func() {
+		globalRegistry = NewRegistry()
+	})
+	return globalRegistry
+}
+
+// NewRegistry 创建 LoRA 适配器注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		hosts:       make(map[string]*hostAdapters),
+		polls:       make(chan struct{}, maxConcurrentPolls),
+		httpClient:  &http.Client{},
+		pollTimeout: getEnvDuration(EnvPollTimeout, 200*time.Millisecond),
+	}
+}
+
+// Update 覆盖主机已加载的适配器列表
+func (r *Registry) Update(ip string, adapters []string) {
+	set := make(map[string]struct{}, len(adapters))
+	for _, a := range adapters {
+		set[a] = struct{}{}
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := r.touch(ip, now)
+	h.adapters = set
+	h.updatedAt = now
+}
+
+// Add 记录主机新加载了一个适配器
+func (r *Registry) Add(ip, adapter string) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := r.touch(ip, now)
+	h.adapters[adapter] = struct{}{}
+	h.updatedAt = now
+}
+
+// touch 获取或创建主机记录并更新最近访问时间，调用方需持有写锁
+func (r *Registry) touch(ip string, now time.Time) *hostAdapters {
+	h, ok := r.hosts[ip]
+	if !ok {
+		h = &hostAdapters{adapters: make(map[string]struct{})}
+		r.hosts[ip] = h
+	}
+	h.seenAt = now
+	return h
+}
+
+// sweep 删除超过 hostIdleTTL 未被选择或更新的主机，调用方需持有写锁
+func (r *Registry) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < sweepInterval {
+		return
+	}
+	r.lastSweep = now
+	for ip, h := range r.hosts {
+		if now.Sub(h.seenAt) >= hostIdleTTL {
+			delete(r.hosts, ip)
+		}
+	}
+}
+
+// Has 判断主机是否已加载指定适配器
+func (r *Registry) Has(ip, adapter string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h, ok := r.hosts[ip]
+	if !ok {
+		return false
+	}
+	_, ok = h.adapters[adapter]
+	return ok
+}
+
+// Count 返回主机已加载的适配器数量
+func (r *Registry) Count(ip string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if h, ok := r.hosts[ip]; ok {
+		return len(h.adapters)
+	}
+	return 0
+}
+
+// RefreshIfStale 如果主机的适配器信息超过 interval 未更新，则异步轮询 /v1/models 刷新
+// 同一主机在一个轮询间隔内只轮询一次（包括失败的轮询），同时轮询的主机数不超过 maxConcurrentPolls
+// interval 为 0 时不轮询，只记录主机访问时间
+func (r *Registry) RefreshIfStale(host types.Host, interval time.Duration) {
+	if host == nil {
+		return
+	}
+	ip := host.Ip()
+	now := time.Now()
+
+	r.mu.Lock()
+	r.sweep(now)
+	h := r.touch(ip, now)
+	if interval <= 0 || now.Sub(h.updatedAt) < interval || now.Sub(h.polledAt) < interval {
+		r.mu.Unlock()
+		return
+	}
+	select {
+	case r.polls <- struct{}{}:
+	default:
+		// 并发轮询数已满，由后续请求再触发
+		r.mu.Unlock()
+		return
+	}
+	h.polledAt = now
+	r.mu.Unlock()
+
+	go func() {
+		defer func() { <-r.polls }()
+
+		adapters, err := r.poll(host)
+		if err != nil {
+			api.LogWarnf("poll lora adapters from %s failed: %v", host.Address(), err)
+			return
+		}
+		r.Update(ip, adapters)
+		api.LogDebugf("poll lora adapters from %s: %v", host.Address(), adapters)
+	}()
+}
+
+// poll 从 vLLM /v1/models 获取已加载的适配器
+// vLLM 将 LoRA 适配器作为 parent 非空的模型返回
+func (r *Registry) poll(host types.Host) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.pollTimeout)
+	defer cancel()
+
+	reqUrl := fmt.Sprintf("http://%s%s", host.Address(), ModelsPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %v, body: %s", resp.StatusCode, string(body))
+	}
+
+	var models struct {
+		Data []struct {
+			ID     string `json:"id"`
+			Parent string `json:"parent"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &models); err != nil {
+		return nil, fmt.Errorf("parse models response error: %w", err)
+	}
+
+	adapters := make([]string, 0, len(models.Data))
+	for _, m := range models.Data {
+		if m.Parent != "" {
+			adapters = append(adapters, m.ID)
+		}
+	}
+	return adapters, nil
+}
+
+// 辅助函数
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		// 尝试解析为毫秒
+		if ms, err := strconv.Atoi(v); err == nil {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return defaultValue
+}
@@ -29,6 +29,7 @@ type Task struct {
 	URL     string
 	Body    []byte
 	TraceId string
+	Tenant  string
 	Timeout time.Duration
 }
 
@@ -44,9 +44,17 @@ const (
 	CacheQueryPath = "/v1/cache/query"
 	// CacheSavePath 缓存保存 API 路径
 	CacheSavePath = "/v1/cache/save"
+	// RateLimitPath 限流计数 API 路径
+	RateLimitPath = "/v1/ratelimit/incr"
+	// QuotaUsagePath 额度用量查询 API 路径
+	QuotaUsagePath = "/v1/quota/usage"
+	// QuotaAddPath 额度用量增加 API 路径
+	QuotaAddPath = "/v1/quota/add"
 
 	// TraceIdHeader Trace ID 请求头
 	TraceIdHeader = "TraceId"
+	// TenantHeader 租户标识请求头
+	TenantHeader = "TenantId"
 
 	// DefaultTopK 默认返回 Top K 缓存位置
 	DefaultTopK = 10
@@ -60,6 +68,7 @@ const (
 	EnvMetadataCenterPort = "METADATA_CENTER_PORT"
 	EnvFetchMetricTimeout = "METADATA_CENTER_FETCH_METRIC_TIMEOUT"
 	EnvFetchCacheTimeout  = "METADATA_CENTER_FETCH_CACHE_TIMEOUT"
+	EnvRateLimitTimeout   = "METADATA_CENTER_RATE_LIMIT_TIMEOUT"
 	EnvUpdateStatsTimeout = "METADATA_CENTER_UPDATE_STATS_TIMEOUT"
 	EnvClientTimeout      = "METADATA_CENTER_CLIENT_TIMEOUT"
 	EnvClientMaxIdleConns = "METADATA_CENTER_CLIENT_MAX_IDLE_CONNS"
@@ -73,6 +82,8 @@ const (
 const (
 	// CtxKeyTraceId 用于在 Context 中传递 Trace ID
 	CtxKeyTraceId types.LBCtxKey = "metacenter.traceId"
+	// CtxKeyTenant 用于在 Context 中传递已认证的租户标识
+	CtxKeyTenant types.LBCtxKey = "metacenter.tenant"
 )
 
 var (
@@ -86,6 +97,8 @@ var (
 	fetchMetricTimeoutOnce sync.Once
 	fetchCacheTimeout      = 100 // ms
 	fetchCacheTimeoutOnce  sync.Once
+	rateLimitTimeout       = 50 // ms
+	rateLimitTimeoutOnce   sync.Once
 
 	// 是否启用 metadata center
 	metadataCenterEnabled          bool
@@ -117,15 +130,18 @@ type InferenceRequest struct {
 	PromptLength int    `json:"prompt_length,omitempty"`
 	Ip           string `json:"ip"`
 	TimeStamp    int64  `json:"timestamp,omitempty"`
+	Tenant       string `json:"tenant,omitempty"`
 	TraceId      string `json:"-"`
 }
 
 // EngineStats 引擎负载统计
 type EngineStats struct {
-	Ip           string `json:"ip"`
-	QueuedReqNum int32  `json:"queued_req_num"`
-	PromptLength int32  `json:"prompt_length"`
-	UpdatedTime  int64  `json:"updated_time"`
+	Ip           string   `json:"ip"`
+	QueuedReqNum int32    `json:"queued_req_num"`
+	PromptLength int32    `json:"prompt_length"`
+	KVUsage      float64  `json:"kv_usage"`
+	LoraAdapters []string `json:"lora_adapters,omitempty"`
+	UpdatedTime  int64    `json:"updated_time"`
 }
 
 // CacheQueryParam 缓存查询参数
@@ -153,9 +169,25 @@ type CacheSaveParam struct {
 	Ip         string   `json:"ip"`
 }
 
+// RateLimitParam 限流计数参数
+type RateLimitParam struct {
+	Key      string `json:"key"`
+	TTLMs    int64  `json:"ttl_ms"`
+	Requests int    `json:"requests"`
+	Tokens   int    `json:"tokens"`
+}
+
+// QuotaAddParam 额度用量增加参数
+type QuotaAddParam struct {
+	Key    string  `json:"key"`
+	Amount float64 `json:"amount"`
+	TTLMs  int64   `json:"ttl_ms"`
+}
+
 // RequestParam 请求参数
 type RequestParam struct {
 	TraceId string
+	Tenant  string
 	HashKey string
 	Method  string
 	Path    string
@@ -261,6 +293,7 @@ func (c *Client) AddRequest(ctx context.Context, requestId, cluster, ip string,
 		Ip:           ip,
 		PromptLength: promptLength,
 		TimeStamp:    time.Now().UnixNano(),
+		Tenant:       types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
 		TraceId:      traceId,
 	}
 
@@ -276,6 +309,7 @@ func (c *Client) AddRequest(ctx context.Context, requestId, cluster, ip string,
 		URL:     LoadStatsPath,
 		Body:    body,
 		TraceId: req.TraceId,
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
 	}
 
 	if err := c.asyncQueue.Dispatch(task); err != nil {
@@ -304,6 +338,7 @@ func (c *Client) DeleteRequest(ctx context.Context, requestId string) error {
 		URL:     LoadStatsPath,
 		Body:    body,
 		TraceId: req.TraceId,
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
 	}
 
 	if err := c.asyncQueue.Dispatch(task); err != nil {
@@ -332,6 +367,7 @@ func (c *Client) DeleteRequestPrompt(ctx context.Context, requestId string) erro
 		URL:     LoadPromptPath,
 		Body:    body,
 		TraceId: req.TraceId,
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
 	}
 
 	if err := c.asyncQueue.Dispatch(task); err != nil {
@@ -351,6 +387,7 @@ func (c *Client) QueryLoad(ctx context.Context, cluster string) (map[string]*typ
 
 	body, err := c.doRequest(ctx, RequestParam{
 		TraceId: traceId,
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
 		HashKey: cluster,
 		Method:  http.MethodGet,
 		Path:    LoadStatsPath,
@@ -384,6 +421,8 @@ func (c *Client) QueryLoad(ctx context.Context, cluster string) (map[string]*typ
 			PromptLength: promptLen,
 			PrefillReqs:  0,
 			TotalReqs:    int(engine.QueuedReqNum),
+			KVUsage:      engine.KVUsage,
+			LoraAdapters: engine.LoraAdapters,
 		}
 	}
 	api.LogDebugf("metadata center load response:%s", string(body))
@@ -410,6 +449,7 @@ func (c *Client) QueryKVCache(ctx context.Context, cluster string, promptHash []
 
 	body, err := c.doRequest(ctx, RequestParam{
 		TraceId: types.GetValueFromCtx(ctx, CtxKeyTraceId, ""),
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
 		HashKey: param.Cluster,
 		Method:  http.MethodPost,
 		Path:    CacheQueryPath,
@@ -466,6 +506,7 @@ func (c *Client) SaveKVCache(ctx context.Context, cluster, ip string, promptHash
 		URL:     CacheSavePath,
 		Body:    body,
 		TraceId: traceId,
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
 	}
 
 	if err := c.asyncQueue.Dispatch(task); err != nil {
@@ -477,6 +518,141 @@ func (c *Client) SaveKVCache(ctx context.Context, cluster, ip string, promptHash
 	return nil
 }
 
+// IncrRateLimit 增加限流计数（同步）
+func (c *Client) IncrRateLimit(ctx context.Context, key string, ttl time.Duration, requests, tokens int) (*types.RateLimitUsage, error) {
+	rateLimitTimeoutOnce.Do(func() {
+		rateLimitTimeout = getEnvInt(EnvRateLimitTimeout, 50)
+	})
+
+	reqBody, err := json.Marshal(&RateLimitParam{
+		Key:      key,
+		TTLMs:    ttl.Milliseconds(),
+		Requests: requests,
+		Tokens:   tokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rate limit request: %v", err)
+	}
+
+	body, err := c.doRequest(ctx, RequestParam{
+		TraceId: types.GetValueFromCtx(ctx, CtxKeyTraceId, ""),
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
+		HashKey: key,
+		Method:  http.MethodPost,
+		Path:    RateLimitPath,
+		Body:    reqBody,
+		Timeout: time.Duration(rateLimitTimeout) * time.Millisecond,
+	})
+	if err != nil {
+		api.LogErrorf("incr rate limit failed, err:%v", err)
+		return nil, fmt.Errorf("failed to incr rate limit: %v", err)
+	}
+
+	type rateLimitResp struct {
+		Data types.RateLimitUsage `json:"data"`
+		Response
+	}
+	var response rateLimitResp
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("parse rate limit response error: %s", err.Error())
+	}
+	return &response.Data, nil
+}
+
+// SettleRateLimit 修正限流计数（异步）
+func (c *Client) SettleRateLimit(ctx context.Context, key string, ttl time.Duration, requests, tokens int) error {
+	traceId := types.GetValueFromCtx(ctx, CtxKeyTraceId, "")
+	body, err := json.Marshal(&RateLimitParam{
+		Key:      key,
+		TTLMs:    ttl.Milliseconds(),
+		Requests: requests,
+		Tokens:   tokens,
+	})
+	if err != nil {
+		return err
+	}
+
+	task := &Task{
+		HashKey: key,
+		Method:  http.MethodPost,
+		URL:     RateLimitPath,
+		Body:    body,
+		TraceId: traceId,
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
+	}
+
+	if err := c.asyncQueue.Dispatch(task); err != nil {
+		api.LogErrorf("settle rate limit failed, key:%s, err:%+v", key, err)
+		return err
+	}
+
+	api.LogDebugf("settle rate limit, trace_id:%s, key:%s, requests:%d, tokens:%d", traceId, key, requests, tokens)
+	return nil
+}
+
+// GetQuotaUsage 查询额度用量（同步）
+func (c *Client) GetQuotaUsage(ctx context.Context, key string) (float64, error) {
+	rateLimitTimeoutOnce.Do(func() {
+		rateLimitTimeout = getEnvInt(EnvRateLimitTimeout, 50)
+	})
+
+	body, err := c.doRequest(ctx, RequestParam{
+		TraceId: types.GetValueFromCtx(ctx, CtxKeyTraceId, ""),
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
+		HashKey: key,
+		Method:  http.MethodGet,
+		Path:    QuotaUsagePath,
+		Query:   map[string]string{"key": key},
+		Timeout: time.Duration(rateLimitTimeout) * time.Millisecond,
+	})
+	if err != nil {
+		api.LogErrorf("query quota usage failed, err:%v", err)
+		return 0, fmt.Errorf("failed to query quota usage: %v", err)
+	}
+
+	type quotaUsageResp struct {
+		Data struct {
+			Used float64 `json:"used"`
+		} `json:"data"`
+		Response
+	}
+	var response quotaUsageResp
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("parse quota usage response error: %s", err.Error())
+	}
+	return response.Data.Used, nil
+}
+
+// AddQuotaUsage 增加额度用量（异步）
+func (c *Client) AddQuotaUsage(ctx context.Context, key string, amount float64, ttl time.Duration) error {
+	traceId := types.GetValueFromCtx(ctx, CtxKeyTraceId, "")
+	body, err := json.Marshal(&QuotaAddParam{
+		Key:    key,
+		Amount: amount,
+		TTLMs:  ttl.Milliseconds(),
+	})
+	if err != nil {
+		return err
+	}
+
+	task := &Task{
+		HashKey: key,
+		Method:  http.MethodPost,
+		URL:     QuotaAddPath,
+		Body:    body,
+		TraceId: traceId,
+		Tenant:  types.GetValueFromCtx(ctx, CtxKeyTenant, ""),
+	}
+
+	if err := c.asyncQueue.Dispatch(task); err != nil {
+		api.LogErrorf("add quota usage failed, key:%s, err:%+v", key, err)
+		return err
+	}
+
+	api.LogDebugf("add quota usage, trace_id:%s, key:%s, amount:%f", traceId, key, amount)
+	return nil
+}
+
 // doRequest 执行 HTTP 请求
 func (c *Client) doRequest(ctx context.Context, reqParam RequestParam) ([]byte, error) {
 	newCtx, cancel := context.WithTimeout(ctx, reqParam.Timeout)
@@ -498,6 +674,9 @@ func (c *Client) doRequest(ctx context.Context, reqParam RequestParam) ([]byte,
 		req.ContentLength = int64(len(reqParam.Body))
 	}
 	req.Header.Set(TraceIdHeader, reqParam.TraceId)
+	if reqParam.Tenant != "" {
+		req.Header.Set(TenantHeader, reqParam.Tenant)
+	}
 
 	if reqParam.Query != nil {
 		q := req.URL.Query()
@@ -529,6 +708,7 @@ func (c *Client) doRequest(ctx context.Context, reqParam RequestParam) ([]byte,
 func (c *Client) HandleRequest(ctx context.Context, task *Task) error {
 	_, err := c.doRequest(ctx, RequestParam{
 		TraceId: task.TraceId,
+		Tenant:  task.Tenant,
 		HashKey: task.HashKey,
 		Method:  task.Method,
 		Path:    task.URL,
@@ -596,6 +776,22 @@ func (n *noopClient) QueryKVCache(ctx context.Context, cluster string, promptHas
 	return nil, errors.New("metadata center disabled")
 }
 
+func (n *noopClient) IncrRateLimit(ctx context.Context, key string, ttl time.Duration, requests, tokens int) (*types.RateLimitUsage, error) {
+	return nil, errors.New("metadata center disabled")
+}
+
+func (n *noopClient) SettleRateLimit(ctx context.Context, key string, ttl time.Duration, requests, tokens int) error {
+	return nil
+}
+
+func (n *noopClient) GetQuotaUsage(ctx context.Context, key string) (float64, error) {
+	return 0, errors.New("metadata center disabled")
+}
+
+func (n *noopClient) AddQuotaUsage(ctx context.Context, key string, amount float64, ttl time.Duration) error {
+	return nil
+}
+
 // GetClientOrNoop 获取客户端，如果 metadata center 未启用则返回空实现
 func GetClientOrNoop() types.MetadataCenter {
 	if !IsEnabled() {
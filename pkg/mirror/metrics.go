@@ -0,0 +1,83 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+// Metrics 按模型上报的镜像指标，与主请求的指标分开统计
+type Metrics struct {
+	// completed 累计成功的镜像请求数
+	completed api.CounterMetric
+	// errors 累计失败的镜像请求数
+	errors api.CounterMetric
+	// dropped 累计因并发已满被丢弃的镜像请求数
+	dropped api.CounterMetric
+	// latencyMs 累计镜像请求延迟（毫秒）
+	latencyMs api.CounterMetric
+	// ttftMs 累计镜像请求首 Token 时间（毫秒）
+	ttftMs api.CounterMetric
+	// inputTokens 累计影子响应的输入 Token 数
+	inputTokens api.CounterMetric
+	// outputTokens 累计影子响应的输出 Token 数
+	outputTokens api.CounterMetric
+}
+
+// NewMetrics 定义模型的镜像指标
+// 平均延迟 = latency_ms / completed
+func NewMetrics(callbacks api.ConfigCallbacks, model string) *Metrics {
+	if callbacks == nil {
+		return nil
+	}
+	prefix := fmt.Sprintf("llm_proxy.mirror.%s.", model)
+	return &Metrics{
+		completed:    callbacks.DefineCounterMetric(prefix + "completed"),
+		errors:       callbacks.DefineCounterMetric(prefix + "errors"),
+		dropped:      callbacks.DefineCounterMetric(prefix + "dropped"),
+		latencyMs:    callbacks.DefineCounterMetric(prefix + "latency_ms"),
+		ttftMs:       callbacks.DefineCounterMetric(prefix + "ttft_ms"),
+		inputTokens:  callbacks.DefineCounterMetric(prefix + "input_tokens"),
+		outputTokens: callbacks.DefineCounterMetric(prefix + "output_tokens"),
+	}
+}
+
+func (m *Metrics) observe(latency, ttft time.Duration, usage Usage) {
+	if m == nil {
+		return
+	}
+	m.completed.Increment(1)
+	m.latencyMs.Increment(latency.Milliseconds())
+	m.ttftMs.Increment(ttft.Milliseconds())
+	m.inputTokens.Increment(int64(usage.PromptTokens))
+	m.outputTokens.Increment(int64(usage.CompletionTokens))
+}
+
+func (m *Metrics) incError() {
+	if m == nil {
+		return
+	}
+	m.errors.Increment(1)
+}
+
+func (m *Metrics) incDropped() {
+	if m == nil {
+		return
+	}
+	m.dropped.Increment(1)
+}
@@ -0,0 +1,266 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mirror 实现请求镜像，将采样的请求异步复制到影子集群
+package mirror
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/ast"
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+const (
+	// EnvMirrorMaxInflight 镜像请求的最大并发数
+	EnvMirrorMaxInflight = "MIRROR_MAX_INFLIGHT"
+	// DefaultMaxInflight 默认镜像请求最大并发数
+	DefaultMaxInflight = 64
+
+	// DefaultTimeout 默认镜像请求超时时间
+	DefaultTimeout = 60 * time.Second
+
+	// Header 镜像请求携带的请求头，影子集群可据此区分镜像流量
+	Header = "x-llm-mirror"
+)
+
+var (
+	globalSender     *Sender
+	globalSenderOnce sync.Once
+
+	metricsMu sync.RWMutex
+	metrics   = map[string]*Metrics{}
+)
+
+// Request 镜像请求
+type Request struct {
+	// TraceId 原请求的 Trace ID
+	TraceId string
+	// Model 原请求匹配的模型，用于上报指标
+	Model string
+	// Cluster 影子集群
+	Cluster string
+	// Host 影子集群中选中的主机
+	Host types.Host
+	// Path 请求路径
+	Path string
+	// Headers 请求头
+	Headers map[string]string
+	// Body 原请求体
+	Body []byte
+	// ModelName 影子请求使用的模型名，为空时不改写
+	ModelName string
+	// Timeout 超时时间
+	Timeout time.Duration
+}
+
+// Sender 镜像请求发送器
+// 超过最大并发数时直接丢弃镜像请求，避免影响主请求
+type Sender struct {
+	httpClient *http.Client
+	slots      chan struct{}
+}
+
+// GetSender 获取全局镜像请求发送器
+func GetSender() *Sender {
+	globalSenderOnce.Do(func() {
+		globalSender = &Sender{
+			httpClient: &http.Client{},
+			slots:      make(chan struct{}, maxInflight()),
+		}
+	})
+	return globalSender
+}
+
+// RegisterMetrics 注册模型的镜像指标
+func RegisterMetrics(model string, m *Metrics) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metrics[model] = m
+}
+
+func getMetrics(model string) *Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	return metrics[model]
+}
+
+// Send 异步发送镜像请求，不等待响应
+func (s *Sender) Send(req *Request) {
+	m := getMetrics(req.Model)
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		m.incDropped()
+		api.LogDebugf("[TraceID: %s] mirror dropped: too many inflight mirror requests", req.TraceId)
+		return
+	}
+
+	go func() {
+		defer func() {
+			<-s.slots
+			if r := recover(); r != nil {
+				api.LogErrorf("[TraceID: %s] mirror panic: %v", req.TraceId, r)
+			}
+		}()
+		s.do(req, m)
+	}()
+}
+
+// do 发送镜像请求并记录影子集群的延迟和 Token 用量
+func (s *Sender) do(req *Request, m *Metrics) {
+	body, stream, err := rewriteBody(req.Body, req.ModelName)
+	if err != nil {
+		m.incError()
+		api.LogWarnf("[TraceID: %s] mirror rewrite body failed: %v", req.TraceId, err)
+		return
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s%s", req.Host.Address(), req.Path)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		m.incError()
+		return
+	}
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Header.Set(Header, "true")
+	httpReq.Header.Set("content-type", "application/json")
+
+	start := time.Now()
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		m.incError()
+		api.LogWarnf("[TraceID: %s] mirror to %s failed: %v", req.TraceId, req.Host.Address(), err)
+		return
+	}
+	defer resp.Body.Close()
+
+	usage, ttft, err := readUsage(resp.Body, stream, start)
+	latency := time.Since(start)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		m.incError()
+		api.LogWarnf("[TraceID: %s] mirror to %s failed: status=%d, err=%v",
+			req.TraceId, req.Host.Address(), resp.StatusCode, err)
+		return
+	}
+
+	m.observe(latency, ttft, usage)
+	api.LogInfof("[TraceID: %s] mirror completed: model=%s, cluster=%s, backend=%s, latency=%dms, ttft=%dms, input_tokens=%d, output_tokens=%d",
+		req.TraceId, req.Model, req.Cluster, req.Host.Ip(), latency.Milliseconds(), ttft.Milliseconds(),
+		usage.PromptTokens, usage.CompletionTokens)
+}
+
+// Usage 影子响应的 Token 用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// rewriteBody 改写影子请求的模型名；流式请求要求返回 usage
+func rewriteBody(body []byte, modelName string) ([]byte, bool, error) {
+	root, err := sonic.Get(body)
+	if err != nil {
+		return nil, false, err
+	}
+	if modelName != "" {
+		if _, err := root.Set("model", ast.NewString(modelName)); err != nil {
+			return nil, false, err
+		}
+	}
+
+	stream, _ := root.Get("stream").Bool()
+	if stream {
+		options := ast.NewObject([]ast.Pair{{Key: "include_usage", Value: ast.NewBool(true)}})
+		if _, err := root.Set("stream_options", options); err != nil {
+			return nil, false, err
+		}
+	}
+
+	data, err := root.MarshalJSON()
+	return data, stream, err
+}
+
+// readUsage 读取完整的影子响应，解析 Token 用量和首 Token 时间
+// 非流式响应的首 Token 时间即响应时间
+func readUsage(r io.Reader, stream bool, start time.Time) (Usage, time.Duration, error) {
+	var usage Usage
+	if !stream {
+		data, err := io.ReadAll(r)
+		ttft := time.Since(start)
+		if err != nil {
+			return usage, ttft, err
+		}
+		var resp struct {
+			Usage *Usage `json:"usage"`
+		}
+		if err := sonic.Unmarshal(data, &resp); err == nil && resp.Usage != nil {
+			usage = *resp.Usage
+		}
+		return usage, ttft, nil
+	}
+
+	var ttft time.Duration
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		payload, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		if ttft == 0 {
+			ttft = time.Since(start)
+		}
+		if !bytes.Contains(payload, []byte(`"usage"`)) {
+			continue
+		}
+		var chunk struct {
+			Usage *Usage `json:"usage"`
+		}
+		if err := sonic.Unmarshal(bytes.TrimSpace(payload), &chunk); err == nil && chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+	}
+	return usage, ttft, scanner.Err()
+}
+
+func maxInflight() int {
+	if v := os.Getenv(EnvMirrorMaxInflight); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxInflight
+}
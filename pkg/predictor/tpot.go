@@ -0,0 +1,82 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package predictor
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// tpotAlpha TPOT 指数加权移动平均系数
+	tpotAlpha = 0.2
+)
+
+var (
+	globalTPOT     *TPOTPredictor
+	globalTPOTOnce sync.Once
+)
+
+// TPOTPredictor 按模型和主机维护的 TPOT（每个输出 Token 的耗时）EWMA
+type TPOTPredictor struct {
+	mu    sync.RWMutex
+	ewmas map[string]float64
+}
+
+// GetTPOTPredictor 获取全局 TPOT 预测器
+func GetTPOTPredictor() *TPOTPredictor {
+	globalTPOTOnce.Do(func() {
+		globalTPOT = NewTPOTPredictor()
+	})
+	return globalTPOT
+}
+
+// NewTPOTPredictor 创建 TPOT 预测器
+func NewTPOTPredictor() *TPOTPredictor {
+	return &TPOTPredictor{
+		ewmas: make(map[string]float64),
+	}
+}
+
+// Observe 记录一次实际测得的 TPOT
+func (t *TPOTPredictor) Observe(model, ip string, tpot time.Duration) {
+	if tpot <= 0 {
+		return
+	}
+	ms := float64(tpot) / float64(time.Millisecond)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range []string{model, modelKey(model, ip)} {
+		if old, ok := t.ewmas[key]; ok {
+			t.ewmas[key] = tpotAlpha*ms + (1-tpotAlpha)*old
+		} else {
+			t.ewmas[key] = ms
+		}
+	}
+}
+
+// Predict 预测指定模型在指定主机上的 TPOT
+// 没有历史数据时返回 false
+func (t *TPOTPredictor) Predict(model, ip string) (time.Duration, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, key := range []string{modelKey(model, ip), model} {
+		if ms, ok := t.ewmas[key]; ok {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	return 0, false
+}
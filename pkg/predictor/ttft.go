@@ -0,0 +1,201 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package predictor 提供基于在线学习的推理延迟预测
+package predictor
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// featureNum 特征数量: [1, prompt 长度(千字节), 排队请求数]
+	featureNum = 3
+
+	// forgettingFactor 递推最小二乘的遗忘因子，越小越偏向近期样本
+	forgettingFactor = 0.99
+	// initialCovariance 协方差矩阵初始值
+	initialCovariance = 1000.0
+	// minSamples 模型可用前所需的最少样本数
+	minSamples = 10
+
+	// defaultBaseTTFT 无历史数据时的基础 TTFT
+	defaultBaseTTFT = 100 * time.Millisecond
+	// defaultTTFTPer1K 无历史数据时每 1000 字节 prompt 增加的 TTFT
+	defaultTTFTPer1K = 50 * time.Millisecond
+)
+
+var (
+	globalTTFT     *TTFTPredictor
+	globalTTFTOnce sync.Once
+)
+
+// rlsModel 递推最小二乘线性回归模型
+// TTFT(ms) = θ0 + θ1 * promptLength / 1000 + θ2 * queueDepth
+type rlsModel struct {
+	mu      sync.Mutex
+	theta   [featureNum]float64
+	p       [featureNum][featureNum]float64
+	samples int
+}
+
+func newRLSModel() *rlsModel {
+	m := &rlsModel{}
+	for i := 0; i < featureNum; i++ {
+		m.p[i][i] = initialCovariance
+	}
+	return m
+}
+
+func features(promptLength, queueDepth int) [featureNum]float64 {
+	return [featureNum]float64{1, float64(promptLength) / 1000, float64(queueDepth)}
+}
+
+// update 使用一个观测样本更新模型
+func (m *rlsModel) update(x [featureNum]float64, y float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// px = P * x
+	var px [featureNum]float64
+	for i := 0; i < featureNum; i++ {
+		for j := 0; j < featureNum; j++ {
+			px[i] += m.p[i][j] * x[j]
+		}
+	}
+
+	// k = P * x / (λ + xᵀ * P * x)
+	denom := forgettingFactor
+	for i := 0; i < featureNum; i++ {
+		denom += x[i] * px[i]
+	}
+	var k [featureNum]float64
+	for i := 0; i < featureNum; i++ {
+		k[i] = px[i] / denom
+	}
+
+	// θ = θ + k * (y - θᵀ * x)
+	e := y
+	for i := 0; i < featureNum; i++ {
+		e -= m.theta[i] * x[i]
+	}
+	for i := 0; i < featureNum; i++ {
+		m.theta[i] += k[i] * e
+	}
+
+	// P = (P - k * xᵀ * P) / λ，P 对称所以 xᵀ * P = pxᵀ
+	for i := 0; i < featureNum; i++ {
+		for j := 0; j < featureNum; j++ {
+			m.p[i][j] = (m.p[i][j] - k[i]*px[j]) / forgettingFactor
+		}
+	}
+	m.samples++
+}
+
+// predict 预测 TTFT（毫秒），样本不足或预测值无效时返回 false
+func (m *rlsModel) predict(x [featureNum]float64) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.samples < minSamples {
+		return 0, false
+	}
+	var y float64
+	for i := 0; i < featureNum; i++ {
+		y += m.theta[i] * x[i]
+	}
+	if y <= 0 {
+		return 0, false
+	}
+	return y, true
+}
+
+// TTFTPredictor 按模型和主机维护的在线 TTFT 预测器
+// 预测时依次使用主机级模型、模型级模型和默认估算公式
+type TTFTPredictor struct {
+	mu     sync.RWMutex
+	models map[string]*rlsModel
+}
+
+// GetTTFTPredictor 获取全局 TTFT 预测器
+func GetTTFTPredictor() *TTFTPredictor {
+	globalTTFTOnce.Do(func() {
+		globalTTFT = NewTTFTPredictor()
+	})
+	return globalTTFT
+}
+
+// NewTTFTPredictor 创建 TTFT 预测器
+func NewTTFTPredictor() *TTFTPredictor {
+	return &TTFTPredictor{
+		models: make(map[string]*rlsModel),
+	}
+}
+
+// Observe 记录一次实际测得的 TTFT
+func (t *TTFTPredictor) Observe(model, ip string, promptLength, queueDepth int, ttft time.Duration) {
+	if ttft <= 0 {
+		return
+	}
+	x := features(promptLength, queueDepth)
+	y := float64(ttft) / float64(time.Millisecond)
+	t.getOrCreate(model).update(x, y)
+	if ip != "" {
+		t.getOrCreate(modelKey(model, ip)).update(x, y)
+	}
+}
+
+// Predict 预测指定模型在指定主机上的 TTFT
+func (t *TTFTPredictor) Predict(model, ip string, promptLength, queueDepth int) time.Duration {
+	x := features(promptLength, queueDepth)
+	for _, key := range []string{modelKey(model, ip), model} {
+		if m := t.get(key); m != nil {
+			if ms, ok := m.predict(x); ok {
+				return time.Duration(ms * float64(time.Millisecond))
+			}
+		}
+	}
+	return defaultTTFT(promptLength)
+}
+
+func (t *TTFTPredictor) get(key string) *rlsModel {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.models[key]
+}
+
+func (t *TTFTPredictor) getOrCreate(key string) *rlsModel {
+	if m := t.get(key); m != nil {
+		return m
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if m, ok := t.models[key]; ok {
+		return m
+	}
+	m := newRLSModel()
+	t.models[key] = m
+	return m
+}
+
+func modelKey(model, ip string) string {
+	return model + "/" + ip
+}
+
+// defaultTTFT 无历史数据时的 TTFT 估算
+func defaultTTFT(promptLength int) time.Duration {
+	return defaultBaseTTFT + time.Duration(promptLength/1000)*defaultTTFTPer1K
+}
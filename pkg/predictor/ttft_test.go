@@ -0,0 +1,116 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package predictor
+
+import (
+	"testing"
+	"time"
+)
+
+// linearTTFT 测试用的 TTFT 生成函数: base + perK * promptLength / 1000 + perReq * queueDepth
+func linearTTFT(base, perK, perReq float64) func(promptLength, queueDepth int) time.Duration {
+	return func(promptLength, queueDepth int) time.Duration {
+		ms := base + perK*float64(promptLength)/1000 + perReq*float64(queueDepth)
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+}
+
+type observation struct {
+	ip  string
+	gen func(promptLength, queueDepth int) time.Duration
+	n   int
+}
+
+func TestTTFTPredictor(t *testing.T) {
+	tests := []struct {
+		name         string
+		observations []observation
+		ip           string
+		promptLength int
+		queueDepth   int
+		want         time.Duration
+	}{
+		{
+			name:         "no samples uses default estimate",
+			promptLength: 4000,
+			want:         defaultBaseTTFT + 4*defaultTTFTPer1K,
+		},
+		{
+			name: "too few samples uses default estimate",
+			observations: []observation{
+				{ip: "10.0.0.1", gen: linearTTFT(200, 30, 10), n: minSamples - 1},
+			},
+			ip:           "10.0.0.1",
+			promptLength: 2000,
+			want:         defaultBaseTTFT + 2*defaultTTFTPer1K,
+		},
+		{
+			name: "non-positive ttft is ignored",
+			observations: []observation{
+				{ip: "10.0.0.1", gen: func(int, int) time.Duration { return 0 }, n: 2 * minSamples},
+			},
+			ip:           "10.0.0.1",
+			promptLength: 1000,
+			want:         defaultBaseTTFT + defaultTTFTPer1K,
+		},
+		{
+			name: "learns linear relation per host",
+			observations: []observation{
+				{ip: "10.0.0.1", gen: linearTTFT(200, 30, 10), n: 200},
+			},
+			ip:           "10.0.0.1",
+			promptLength: 5000,
+			queueDepth:   4,
+			want:         time.Duration(200+30*5+10*4) * time.Millisecond,
+		},
+		{
+			name: "host model preferred over model level",
+			observations: []observation{
+				{ip: "10.0.0.1", gen: linearTTFT(100, 10, 5), n: 200},
+				{ip: "10.0.0.2", gen: linearTTFT(900, 10, 5), n: 200},
+			},
+			ip:           "10.0.0.2",
+			promptLength: 1000,
+			queueDepth:   2,
+			want:         time.Duration(900+10+10) * time.Millisecond,
+		},
+		{
+			name: "unknown host falls back to model level",
+			observations: []observation{
+				{ip: "10.0.0.1", gen: linearTTFT(300, 20, 0), n: 200},
+			},
+			ip:           "10.0.0.9",
+			promptLength: 3000,
+			want:         time.Duration(300+20*3) * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewTTFTPredictor()
+			for _, o := range tt.observations {
+				for i := 0; i < o.n; i++ {
+					promptLength, queueDepth := 500*(i%11), i%7
+					p.Observe("qwen", o.ip, promptLength, queueDepth, o.gen(promptLength, queueDepth))
+				}
+			}
+
+			got := p.Predict("qwen", tt.ip, tt.promptLength, tt.queueDepth)
+			if diff := got - tt.want; diff < -tt.want/20 || diff > tt.want/20 {
+				t.Errorf("Predict() = %s, want %s (±5%%)", got, tt.want)
+			}
+		})
+	}
+}
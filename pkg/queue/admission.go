@@ -0,0 +1,53 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrAdmissionLimit 优先级并发请求数超过限制
+var ErrAdmissionLimit = errors.New("priority inflight limit exceeded")
+
+// 按优先级统计的本网关实例并发请求数
+var (
+	inflight   = make(map[string]int)
+	inflightMu sync.Mutex
+)
+
+// Admit 按优先级准入请求，limit 为 0 时不限制
+// 准入成功后需要在请求结束时调用 Release
+func Admit(class string, limit int) error {
+	inflightMu.Lock()
+	defer inflightMu.Unlock()
+
+	if limit > 0 && inflight[class] >= limit {
+		return fmt.Errorf("%w: priority=%s, inflight=%d", ErrAdmissionLimit, class, inflight[class])
+	}
+	inflight[class]++
+	return nil
+}
+
+// Release 释放优先级并发请求数
+func Release(class string) {
+	inflightMu.Lock()
+	defer inflightMu.Unlock()
+
+	if inflight[class] > 0 {
+		inflight[class]--
+	}
+}
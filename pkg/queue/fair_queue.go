@@ -0,0 +1,366 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queue 实现后端饱和时的网关侧请求排队
+package queue
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+const (
+	// DefaultMaxSize 默认队列最大长度
+	DefaultMaxSize = 1000
+	// DefaultMaxWait 默认最长排队时间
+	DefaultMaxWait = 10 * time.Second
+	// DefaultDispatchInterval 默认出队检查间隔
+	DefaultDispatchInterval = 50 * time.Millisecond
+)
+
+var (
+	// ErrQueueFull 队列已满
+	ErrQueueFull = errors.New("gateway queue is full")
+
+	// 全局队列注册表，按模型名索引
+	queues   = make(map[string]*FairQueue)
+	queuesMu sync.RWMutex
+)
+
+// Options 队列配置
+type Options struct {
+	// MaxSize 队列最大长度
+	MaxSize int
+	// MaxWait 最长排队时间
+	MaxWait time.Duration
+	// DispatchInterval 出队检查间隔
+	DispatchInterval time.Duration
+}
+
+// Flow 排队流，同一流内先进先出，同一优先级等级的不同流之间按权重公平调度
+type Flow struct {
+	// Key 流标识，如租户和优先级
+	Key string
+	// Weight 流权重，越大分到的出队机会越多
+	Weight int
+	// Class 优先级名称
+	Class string
+	// Level 优先级等级，越小越优先，高等级请求总是先于低等级请求出队
+	Level int
+	// MaxQueued 该优先级的最大排队请求数，0 表示不限制
+	MaxQueued int
+}
+
+// Waiter 排队中的请求
+type Waiter struct {
+	flow       string
+	class      string
+	level      int
+	finish     float64
+	index      int
+	ready      chan struct{}
+	enqueuedAt time.Time
+}
+
+// waiterHeap 按虚拟完成时间排序的最小堆
+type waiterHeap []*Waiter
+
+func (h waiterHeap) Len() int { return len(h) }
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].level != h[j].level {
+		return h[i].level < h[j].level
+	}
+	if h[i].finish == h[j].finish {
+		return h[i].enqueuedAt.Before(h[j].enqueuedAt)
+	}
+	return h[i].finish < h[j].finish
+}
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *waiterHeap) Push(x any) {
+	w := x.(*Waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+func (h *waiterHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
+
+// FairQueue 加权公平队列
+// 采用加权公平排队 (WFQ)：每个请求的虚拟完成时间 = max(当前虚拟时间, 流上一个请求的完成时间) + 1/权重，
+// 每次出队虚拟完成时间最小的请求。同一时刻只放行一个请求，由其重新选择主机后决定继续排队还是转发。
+type FairQueue struct {
+	mu          sync.Mutex
+	name        string
+	opts        Options
+	waiters     waiterHeap
+	virtualTime float64
+	lastFinish  map[string]float64
+	classCount  map[string]int
+	inflight    *Waiter
+	notify      chan struct{}
+	stop        chan struct{}
+	closed      bool
+	metrics     *Metrics
+	now         func() time.Time
+}
+
+// Register 注册或更新模型的排队队列
+func Register(model string, opts Options, metrics *Metrics) *FairQueue {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = DefaultMaxWait
+	}
+	if opts.DispatchInterval <= 0 {
+		opts.DispatchInterval = DefaultDispatchInterval
+	}
+
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+	if q, ok := queues[model]; ok {
+		q.mu.Lock()
+		q.opts = opts
+		if metrics != nil {
+			q.metrics = metrics
+		}
+		q.mu.Unlock()
+		return q
+	}
+
+	q := &FairQueue{
+		name:       model,
+		opts:       opts,
+		lastFinish: make(map[string]float64),
+		classCount: make(map[string]int),
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		metrics:    metrics,
+		now:        time.Now,
+	}
+	queues[model] = q
+	go q.dispatch()
+
+	api.LogInfof("gateway queue registered for model %s, max_size=%d, max_wait=%s",
+		model, opts.MaxSize, opts.MaxWait)
+	return q
+}
+
+// Retain 注销不在 models 中的排队队列，用于配置重新加载后删除的队列配置
+// 被注销的队列停止出队协程并放行所有排队中的请求，由请求自行重新选择主机
+func Retain(models map[string]bool) {
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+	for model, q := range queues {
+		if models[model] {
+			continue
+		}
+		delete(queues, model)
+		q.close()
+		api.LogInfof("gateway queue unregistered for model %s", model)
+	}
+}
+
+// Get 获取模型的排队队列，未注册时返回 nil
+func Get(model string) *FairQueue {
+	queuesMu.RLock()
+	defer queuesMu.RUnlock()
+	return queues[model]
+}
+
+// MaxWait 获取最长排队时间
+func (q *FairQueue) MaxWait() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.opts.MaxWait
+}
+
+// Len 获取当前排队请求数
+func (q *FairQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters)
+}
+
+// Enqueue 请求入队
+func (q *FairQueue) Enqueue(flow Flow) (*Waiter, error) {
+	weight := flow.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.waiters) >= q.opts.MaxSize {
+		q.metrics.incRejected()
+		return nil, fmt.Errorf("%w: model=%s, size=%d", ErrQueueFull, q.name, len(q.waiters))
+	}
+	if flow.MaxQueued > 0 && q.classCount[flow.Class] >= flow.MaxQueued {
+		q.metrics.incRejected()
+		return nil, fmt.Errorf("%w: model=%s, priority=%s, size=%d", ErrQueueFull, q.name, flow.Class, q.classCount[flow.Class])
+	}
+
+	start := math.Max(q.virtualTime, q.lastFinish[flow.Key])
+	w := &Waiter{
+		flow:       flow.Key,
+		class:      flow.Class,
+		level:      flow.Level,
+		finish:     start + 1/float64(weight),
+		ready:      make(chan struct{}),
+		enqueuedAt: q.now(),
+	}
+	q.lastFinish[flow.Key] = w.finish
+	q.classCount[w.class]++
+	heap.Push(&q.waiters, w)
+	q.metrics.setDepth(len(q.waiters))
+	return w, nil
+}
+
+// Ready 返回请求被放行时关闭的 channel
+func (q *FairQueue) Ready(w *Waiter) <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return w.ready
+}
+
+// Requeue 放行的请求仍然找不到可用主机，以原虚拟完成时间重新入队
+// 队列已注销时不再入队，返回 false
+func (q *FairQueue) Requeue(w *Waiter) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.inflight == w {
+		q.inflight = nil
+	}
+	if q.closed {
+		return false
+	}
+	w.ready = make(chan struct{})
+	q.classCount[w.class]++
+	heap.Push(&q.waiters, w)
+	q.metrics.setDepth(len(q.waiters))
+	return true
+}
+
+// Done 放行的请求已转发，结束排队
+func (q *FairQueue) Done(w *Waiter) {
+	q.mu.Lock()
+	if q.inflight == w {
+		q.inflight = nil
+	}
+	q.metrics.observeWait(q.now().Sub(w.enqueuedAt))
+	q.mu.Unlock()
+
+	// 继续放行下一个请求
+	q.Notify()
+}
+
+// Cancel 请求超时或客户端断开，移出队列
+func (q *FairQueue) Cancel(w *Waiter, timeout bool) {
+	q.mu.Lock()
+	if w.index >= 0 && w.index < len(q.waiters) && q.waiters[w.index] == w {
+		heap.Remove(&q.waiters, w.index)
+		q.classCount[w.class]--
+	}
+	if q.inflight == w {
+		q.inflight = nil
+	}
+	if timeout {
+		q.metrics.incTimeout()
+	}
+	q.metrics.setDepth(len(q.waiters))
+	q.mu.Unlock()
+
+	q.Notify()
+}
+
+// Notify 通知队列有后端容量释放，尝试放行下一个请求
+func (q *FairQueue) Notify() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch 出队协程，在收到通知或定时检查时放行一个请求
+func (q *FairQueue) dispatch() {
+	for {
+		q.mu.Lock()
+		interval := q.opts.DispatchInterval
+		q.mu.Unlock()
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-q.notify:
+		case <-timer.C:
+		case <-q.stop:
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+		q.releaseOne()
+	}
+}
+
+// close 停止出队协程并放行所有排队中的请求
+func (q *FairQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.stop)
+	for _, w := range q.waiters {
+		w.index = -1
+		close(w.ready)
+	}
+	q.waiters = nil
+	q.classCount = make(map[string]int)
+	q.inflight = nil
+	q.metrics.setDepth(0)
+}
+
+// releaseOne 放行虚拟完成时间最小的请求
+func (q *FairQueue) releaseOne() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.inflight != nil || len(q.waiters) == 0 {
+		return
+	}
+	w := heap.Pop(&q.waiters).(*Waiter)
+	q.classCount[w.class]--
+	q.virtualTime = math.Max(q.virtualTime, w.finish)
+	q.inflight = w
+	q.metrics.setDepth(len(q.waiters))
+	close(w.ready)
+}
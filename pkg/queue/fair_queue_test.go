@@ -0,0 +1,207 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestQueue 创建不启动出队协程的队列，时钟每次调用前进 1ms
+func newTestQueue(opts Options) *FairQueue {
+	clock := time.Unix(0, 0)
+	return &FairQueue{
+		name:       "test",
+		opts:       opts,
+		lastFinish: make(map[string]float64),
+		classCount: make(map[string]int),
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		now: func() time.Time {
+			clock = clock.Add(time.Millisecond)
+			return clock
+		},
+	}
+}
+
+// release 放行一个请求并结束其排队，返回流标识
+func release(t *testing.T, q *FairQueue) string {
+	t.Helper()
+	q.releaseOne()
+	w := q.inflight
+	if w == nil {
+		t.Fatal("releaseOne() released nothing")
+	}
+	select {
+	case <-w.ready:
+	default:
+		t.Fatalf("released waiter of flow %s is not ready", w.flow)
+	}
+	q.Done(w)
+	return w.flow
+}
+
+func TestFairQueueOrder(t *testing.T) {
+	flows := map[string]Flow{
+		"a":     {Key: "a", Weight: 1},
+		"b":     {Key: "b", Weight: 1},
+		"heavy": {Key: "heavy", Weight: 2},
+		"zero":  {Key: "zero"},
+		"batch": {Key: "batch", Weight: 1, Class: "batch", Level: 1},
+		"rt":    {Key: "rt", Weight: 1, Class: "interactive", Level: 0},
+	}
+
+	// step 为流名称时入队，为空时放行一个请求
+	tests := []struct {
+		name  string
+		steps []string
+		want  []string
+	}{
+		{
+			name:  "equal weights alternate",
+			steps: []string{"a", "a", "b", "b"},
+			want:  []string{"a", "b", "a", "b"},
+		},
+		{
+			name:  "weight 2 gets twice the share",
+			steps: []string{"heavy", "heavy", "heavy", "heavy", "a", "a"},
+			want:  []string{"heavy", "heavy", "a", "heavy", "heavy", "a"},
+		},
+		{
+			name:  "zero weight treated as 1",
+			steps: []string{"zero", "zero", "b", "b"},
+			want:  []string{"zero", "b", "zero", "b"},
+		},
+		{
+			name:  "higher level always first",
+			steps: []string{"batch", "batch", "rt", "rt"},
+			want:  []string{"rt", "rt", "batch", "batch"},
+		},
+		{
+			name:  "new flow starts at current virtual time",
+			steps: []string{"a", "a", "a", "", "", "b"},
+			want:  []string{"a", "a", "a", "b"},
+		},
+		{
+			name:  "idle flow does not bank credit",
+			steps: []string{"a", "", "a", "a", "", "b", "b"},
+			want:  []string{"a", "a", "a", "b", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(Options{MaxSize: 100})
+			var got []string
+			for _, step := range tt.steps {
+				if step == "" {
+					got = append(got, release(t, q))
+					continue
+				}
+				if _, err := q.Enqueue(flows[step]); err != nil {
+					t.Fatalf("Enqueue(%s) error: %v", step, err)
+				}
+			}
+			for q.Len() > 0 {
+				got = append(got, release(t, q))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("release order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFairQueueLimits(t *testing.T) {
+	interactive := Flow{Key: "rt", Weight: 1, Class: "interactive", MaxQueued: 1}
+	batch := Flow{Key: "batch", Weight: 1, Class: "batch"}
+
+	tests := []struct {
+		name    string
+		maxSize int
+		flows   []Flow
+		wantErr []bool
+	}{
+		{
+			name:    "queue full",
+			maxSize: 2,
+			flows:   []Flow{batch, batch, batch},
+			wantErr: []bool{false, false, true},
+		},
+		{
+			name:    "class max queued",
+			maxSize: 10,
+			flows:   []Flow{interactive, interactive, batch},
+			wantErr: []bool{false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(Options{MaxSize: tt.maxSize})
+			for i, flow := range tt.flows {
+				_, err := q.Enqueue(flow)
+				if gotErr := err != nil; gotErr != tt.wantErr[i] {
+					t.Fatalf("Enqueue #%d error = %v, want error %v", i, err, tt.wantErr[i])
+				}
+				if err != nil && !errors.Is(err, ErrQueueFull) {
+					t.Fatalf("Enqueue #%d error = %v, want ErrQueueFull", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFairQueueCancelAndRequeue(t *testing.T) {
+	q := newTestQueue(Options{MaxSize: 10})
+	a1, _ := q.Enqueue(Flow{Key: "a", Weight: 1, Class: "c", MaxQueued: 2})
+	a2, _ := q.Enqueue(Flow{Key: "a", Weight: 1, Class: "c", MaxQueued: 2})
+
+	// 取消后释放该优先级的排队名额
+	q.Cancel(a2, true)
+	if q.Len() != 1 || q.classCount["c"] != 1 {
+		t.Fatalf("after Cancel: len=%d, class count=%d, want 1, 1", q.Len(), q.classCount["c"])
+	}
+
+	// 放行的请求未完成前不放行下一个，重新入队后保持原有次序
+	q.releaseOne()
+	if q.inflight != a1 {
+		t.Fatal("releaseOne() did not release the first waiter")
+	}
+	if _, err := q.Enqueue(Flow{Key: "b", Weight: 1}); err != nil {
+		t.Fatal(err)
+	}
+	q.releaseOne()
+	if q.inflight != a1 {
+		t.Fatal("releaseOne() released another waiter while one is in flight")
+	}
+	if !q.Requeue(a1) {
+		t.Fatal("Requeue() = false on open queue")
+	}
+	if got := release(t, q); got != "a" {
+		t.Errorf("after Requeue released %s, want a", got)
+	}
+
+	// 关闭后放行所有请求，不再重新入队
+	q.close()
+	if q.Len() != 0 {
+		t.Errorf("after close: len=%d, want 0", q.Len())
+	}
+	if q.Requeue(a1) {
+		t.Error("Requeue() = true on closed queue")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+// Metrics 按模型上报的排队指标
+type Metrics struct {
+	// depth 当前排队请求数
+	depth api.GaugeMetric
+	// waitMs 累计排队时间（毫秒）
+	waitMs api.CounterMetric
+	// dispatched 累计出队请求数
+	dispatched api.CounterMetric
+	// timeout 累计排队超时请求数
+	timeout api.CounterMetric
+	// rejected 累计因队列已满被拒绝的请求数
+	rejected api.CounterMetric
+}
+
+// NewMetrics 定义模型的排队指标
+// 平均排队时间 = wait_ms / dispatched
+func NewMetrics(callbacks api.ConfigCallbacks, model string) *Metrics {
+	if callbacks == nil {
+		return nil
+	}
+	prefix := fmt.Sprintf("llm_proxy.queue.%s.", model)
+	return &Metrics{
+		depth:      callbacks.DefineGaugeMetric(prefix + "depth"),
+		waitMs:     callbacks.DefineCounterMetric(prefix + "wait_ms"),
+		dispatched: callbacks.DefineCounterMetric(prefix + "dispatched"),
+		timeout:    callbacks.DefineCounterMetric(prefix + "timeout"),
+		rejected:   callbacks.DefineCounterMetric(prefix + "rejected"),
+	}
+}
+
+func (m *Metrics) setDepth(depth int) {
+	if m == nil {
+		return
+	}
+	m.depth.Record(uint64(depth))
+}
+
+func (m *Metrics) observeWait(wait time.Duration) {
+	if m == nil {
+		return
+	}
+	m.waitMs.Increment(wait.Milliseconds())
+	m.dispatched.Increment(1)
+}
+
+func (m *Metrics) incTimeout() {
+	if m == nil {
+		return
+	}
+	m.timeout.Increment(1)
+}
+
+func (m *Metrics) incRejected() {
+	if m == nil {
+		return
+	}
+	m.rejected.Increment(1)
+}
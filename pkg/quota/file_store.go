@@ -0,0 +1,207 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+)
+
+const (
+	// EnvQuotaFilePath 文件存储路径
+	EnvQuotaFilePath = "QUOTA_FILE_PATH"
+	// EnvQuotaFileFlushInterval 文件存储落盘间隔
+	EnvQuotaFileFlushInterval = "QUOTA_FILE_FLUSH_INTERVAL"
+
+	// DefaultQuotaFilePath 默认文件存储路径
+	DefaultQuotaFilePath = "/var/lib/llm-proxy/quota.json"
+	// DefaultQuotaFileFlushInterval 默认文件存储落盘间隔
+	DefaultQuotaFileFlushInterval = 10 * time.Second
+)
+
+// fileEntry 文件存储中的一条额度计数
+type fileEntry struct {
+	Used     float64 `json:"used"`
+	ExpireAt int64   `json:"expire_at"`
+}
+
+// FileStore 单节点文件额度存储
+// 计数保存在内存中并定期落盘，启动时从文件恢复，过期的计数在落盘时清理
+type FileStore struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*fileEntry
+	// version 每次修改递增，flushed 为最近一次成功落盘时的 version
+	version uint64
+	flushed uint64
+}
+
+// NewFileStore 创建文件额度存储
+func NewFileStore(path string, flushInterval time.Duration) *FileStore {
+	s := &FileStore{
+		path:    path,
+		entries: make(map[string]*fileEntry),
+	}
+	if err := s.load(); err != nil {
+		api.LogWarnf("load quota file %s failed: %v", path, err)
+	}
+	go s.flushLoop(flushInterval)
+	return s
+}
+
+// Get 实现 Store 接口
+func (s *FileStore) Get(ctx context.Context, key string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || entry.ExpireAt <= time.Now().Unix() {
+		return 0, nil
+	}
+	return entry.Used, nil
+}
+
+// Add 实现 Store 接口
+func (s *FileStore) Add(ctx context.Context, key string, amount float64, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok || entry.ExpireAt <= now.Unix() {
+		entry = &fileEntry{ExpireAt: now.Add(ttl).Unix()}
+		s.entries[key] = entry
+	}
+	entry.Used += amount
+	s.version++
+	return nil
+}
+
+// Reserve 实现 Store 接口，查询和预占在同一把锁内完成
+func (s *FileStore) Reserve(ctx context.Context, key string, amount, limit float64, ttl time.Duration) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok || entry.ExpireAt <= now.Unix() {
+		entry = &fileEntry{ExpireAt: now.Add(ttl).Unix()}
+		s.entries[key] = entry
+	}
+	used := entry.Used
+	if used >= limit {
+		return used, false, nil
+	}
+	entry.Used += amount
+	s.version++
+	return used, true, nil
+}
+
+// load 从文件恢复计数
+func (s *FileStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]*fileEntry)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range entries {
+		if entry != nil {
+			s.entries[key] = entry
+		}
+	}
+	return nil
+}
+
+// flushLoop 定期落盘
+func (s *FileStore) flushLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.flush(); err != nil {
+			api.LogWarnf("flush quota file %s failed: %v", s.path, err)
+		}
+	}
+}
+
+// flush 清理过期计数并写入临时文件后原子替换
+func (s *FileStore) flush() error {
+	s.mu.Lock()
+	version := s.version
+	if version == s.flushed {
+		s.mu.Unlock()
+		return nil
+	}
+	now := time.Now().Unix()
+	for key, entry := range s.entries {
+		if entry.ExpireAt <= now {
+			delete(s.entries, key)
+		}
+	}
+	data, err := json.Marshal(s.entries)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	// 落盘成功后才标记，失败时下一轮重试
+	s.mu.Lock()
+	s.flushed = version
+	s.mu.Unlock()
+	return nil
+}
+
+func fileStorePath() string {
+	if v := os.Getenv(EnvQuotaFilePath); v != "" {
+		return v
+	}
+	return DefaultQuotaFilePath
+}
+
+func fileStoreFlushInterval() time.Duration {
+	if v := os.Getenv(EnvQuotaFileFlushInterval); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return DefaultQuotaFileFlushInterval
+}
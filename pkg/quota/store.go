@@ -0,0 +1,119 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota 实现按租户的长周期额度统计
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/istio-llm-filter/pkg/metadata"
+)
+
+// 额度存储类型
+const (
+	// StoreFile 单节点文件存储
+	StoreFile = "file"
+	// StoreShared 基于 Metadata-Center 的多实例共享存储
+	StoreShared = "shared"
+)
+
+// Store 额度存储
+type Store interface {
+	// Get 获取已使用的额度
+	Get(ctx context.Context, key string) (float64, error)
+	// Add 增加已使用的额度，amount 为负数时退还，ttl 为计数过期时间
+	Add(ctx context.Context, key string, amount float64, ttl time.Duration) error
+	// Reserve 已使用的额度未达到 limit 时预占 amount，返回预占前已使用的额度和是否预占成功
+	Reserve(ctx context.Context, key string, amount, limit float64, ttl time.Duration) (float64, bool, error)
+}
+
+// StoreFactory 额度存储工厂函数
+type StoreFactory func() Store
+
+var (
+	storeFactories = make(map[string]StoreFactory)
+	stores         = make(map[string]Store)
+	storesMu       sync.Mutex
+)
+
+func init() {
+	RegisterStore(StoreFile, func() Store { return NewFileStore(fileStorePath(), fileStoreFlushInterval()) })
+	RegisterStore(StoreShared, func() Store { return &SharedStore{} })
+}
+
+// RegisterStore 注册额度存储
+func RegisterStore(name string, factory StoreFactory) {
+	storeFactories[name] = factory
+}
+
+// HasStore 判断额度存储类型是否已注册
+func HasStore(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := storeFactories[name]
+	return ok
+}
+
+// GetStore 获取额度存储，同一类型的存储全局只创建一次，未知类型返回 nil
+func GetStore(name string) Store {
+	if name == "" {
+		name = StoreFile
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if store, ok := stores[name]; ok {
+		return store
+	}
+	factory, ok := storeFactories[name]
+	if !ok {
+		return nil
+	}
+	store := factory()
+	stores[name] = store
+	return store
+}
+
+// SharedStore 基于 Metadata-Center 的额度存储
+type SharedStore struct{}
+
+// Get 实现 Store 接口
+func (s *SharedStore) Get(ctx context.Context, key string) (float64, error) {
+	return metadata.GetClientOrNoop().GetQuotaUsage(ctx, key)
+}
+
+// Add 实现 Store 接口
+func (s *SharedStore) Add(ctx context.Context, key string, amount float64, ttl time.Duration) error {
+	return metadata.GetClientOrNoop().AddQuotaUsage(ctx, key, amount, ttl)
+}
+
+// Reserve 实现 Store 接口
+// 查询和预占分两次请求 Metadata-Center，且预占异步写入，多实例并发时可能少量超出额度
+func (s *SharedStore) Reserve(ctx context.Context, key string, amount, limit float64, ttl time.Duration) (float64, bool, error) {
+	used, err := s.Get(ctx, key)
+	if err != nil {
+		return 0, false, err
+	}
+	if used >= limit {
+		return used, false, nil
+	}
+	if err := s.Add(ctx, key, amount, ttl); err != nil {
+		return used, false, err
+	}
+	return used, true, nil
+}
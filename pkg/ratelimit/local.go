@@ -0,0 +1,148 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理已补满的令牌桶的间隔
+const sweepInterval = time.Minute
+
+func init() {
+	RegisterLimiter(ModeLocal, NewLocalLimiter())
+}
+
+// bucket 令牌桶，容量为每分钟配额，按配额匀速补充
+type bucket struct {
+	requests float64
+	tokens   float64
+	last     time.Time
+	// fullAt 桶补满的时间，之后删除桶与重新创建满桶等价
+	fullAt time.Time
+}
+
+// updateFullAt 根据当前余量计算桶补满的时间
+func (b *bucket) updateFullAt(limit Limit) {
+	wait := 0.0
+	if rpm := float64(limit.RequestsPerMinute); rpm > 0 {
+		wait = math.Max(wait, (rpm-b.requests)/rpm)
+	}
+	if tpm := float64(limit.TokensPerMinute); tpm > 0 {
+		wait = math.Max(wait, (tpm-b.tokens)/tpm)
+	}
+	b.fullAt = b.last.Add(time.Duration(wait * float64(time.Minute)))
+}
+
+// LocalLimiter 本网关实例内的令牌桶限流器
+// 定期删除已补满的桶，桶的数量只与近期活跃的限流键有关
+type LocalLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLocalLimiter 创建本地令牌桶限流器
+func NewLocalLimiter() *LocalLimiter {
+	return &LocalLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Acquire 实现 Limiter 接口
+// 单个请求的 Token 数超过桶容量时，只要求桶是满的
+func (l *LocalLimiter) Acquire(ctx context.Context, key string, limit Limit, tokens int) (*Ticket, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	b := l.refill(key, limit, now)
+
+	if rpm := float64(limit.RequestsPerMinute); rpm > 0 && b.requests < 1 {
+		return nil, &ExceededError{
+			Kind:       KindRequests,
+			Limit:      limit.RequestsPerMinute,
+			Requested:  1,
+			RetryAfter: time.Duration((1 - b.requests) / rpm * float64(time.Minute)),
+		}
+	}
+	if tpm := float64(limit.TokensPerMinute); tpm > 0 {
+		need := math.Min(float64(tokens), tpm)
+		if b.tokens < need {
+			return nil, &ExceededError{
+				Kind:       KindTokens,
+				Limit:      limit.TokensPerMinute,
+				Requested:  tokens,
+				RetryAfter: time.Duration((need - b.tokens) / tpm * float64(time.Minute)),
+			}
+		}
+	}
+
+	b.requests--
+	b.tokens -= float64(tokens)
+	b.updateFullAt(limit)
+	return &Ticket{key: key, tokens: tokens}, nil
+}
+
+// Settle 实现 Limiter 接口
+// 实际用量超出预估时桶可以为负，后续请求需要等待补充
+func (l *LocalLimiter) Settle(ctx context.Context, ticket *Ticket, limit Limit, actualTokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(ticket.key, limit, l.now())
+	b.tokens -= float64(actualTokens - ticket.tokens)
+	if tpm := float64(limit.TokensPerMinute); tpm > 0 {
+		b.tokens = math.Min(b.tokens, tpm)
+	}
+	b.updateFullAt(limit)
+}
+
+// sweep 删除已补满的桶
+func (l *LocalLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if !now.Before(b.fullAt) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// refill 按经过的时间补充令牌，新的桶是满的
+func (l *LocalLimiter) refill(key string, limit Limit, now time.Time) *bucket {
+	rpm := float64(limit.RequestsPerMinute)
+	tpm := float64(limit.TokensPerMinute)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{requests: rpm, tokens: tpm, last: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	elapsed := now.Sub(b.last).Minutes()
+	b.requests = math.Min(rpm, b.requests+elapsed*rpm)
+	b.tokens = math.Min(tpm, b.tokens+elapsed*tpm)
+	b.last = now
+	return b
+}
@@ -0,0 +1,173 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLimiter 创建使用可控时钟的本地限流器
+func newTestLimiter() (*LocalLimiter, *time.Time) {
+	clock := time.Unix(1000, 0)
+	l := NewLocalLimiter()
+	l.now = func() time.Time { return clock }
+	return l, &clock
+}
+
+func TestLocalLimiter(t *testing.T) {
+	type step struct {
+		// advance 执行前推进的时间
+		advance time.Duration
+		// settle 大于等于 0 时按该实际 Token 数结算上一次预扣，否则预扣 tokens
+		settle int
+		tokens int
+		// wantKind 期望的超限类型，为空表示不超限
+		wantKind  string
+		wantRetry time.Duration
+	}
+	acquire := func(tokens int) step { return step{settle: -1, tokens: tokens} }
+
+	tests := []struct {
+		name  string
+		limit Limit
+		steps []step
+	}{
+		{
+			name:  "unlimited",
+			limit: Limit{},
+			steps: []step{acquire(1000), acquire(1000), acquire(1000)},
+		},
+		{
+			name:  "requests per minute exceeded",
+			limit: Limit{RequestsPerMinute: 2},
+			steps: []step{
+				acquire(0),
+				acquire(0),
+				{settle: -1, wantKind: KindRequests, wantRetry: 30 * time.Second},
+			},
+		},
+		{
+			name:  "requests refill over time",
+			limit: Limit{RequestsPerMinute: 2},
+			steps: []step{
+				acquire(0),
+				acquire(0),
+				{settle: -1, advance: 30 * time.Second},
+				{settle: -1, wantKind: KindRequests, wantRetry: 30 * time.Second},
+			},
+		},
+		{
+			name:  "tokens per minute exceeded",
+			limit: Limit{TokensPerMinute: 100},
+			steps: []step{
+				acquire(60),
+				{settle: -1, tokens: 60, wantKind: KindTokens, wantRetry: 12 * time.Second},
+			},
+		},
+		{
+			name:  "oversized request only needs a full bucket",
+			limit: Limit{TokensPerMinute: 100},
+			steps: []step{
+				acquire(150),
+				{settle: -1, tokens: 1, wantKind: KindTokens, wantRetry: 30600 * time.Millisecond},
+			},
+		},
+		{
+			name:  "settle charges usage over the estimate",
+			limit: Limit{TokensPerMinute: 100},
+			steps: []step{
+				acquire(10),
+				{settle: 90},
+				{settle: -1, tokens: 20, wantKind: KindTokens, wantRetry: 6 * time.Second},
+			},
+		},
+		{
+			name:  "settle refund is capped at capacity",
+			limit: Limit{TokensPerMinute: 100},
+			steps: []step{
+				acquire(50),
+				{settle: 0, advance: 30 * time.Second},
+				acquire(100),
+				{settle: -1, tokens: 1, wantKind: KindTokens, wantRetry: 600 * time.Millisecond},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter()
+			var ticket *Ticket
+			for i, s := range tt.steps {
+				*clock = clock.Add(s.advance)
+				if s.settle >= 0 {
+					l.Settle(context.Background(), ticket, tt.limit, s.settle)
+					continue
+				}
+
+				got, err := l.Acquire(context.Background(), "tenant", tt.limit, s.tokens)
+				if s.wantKind == "" {
+					if err != nil {
+						t.Fatalf("step %d: Acquire() error = %v", i, err)
+					}
+					ticket = got
+					continue
+				}
+				var exceeded *ExceededError
+				if !errors.As(err, &exceeded) {
+					t.Fatalf("step %d: Acquire() error = %v, want *ExceededError", i, err)
+				}
+				if exceeded.Kind != s.wantKind || exceeded.RetryAfter.Round(time.Millisecond) != s.wantRetry {
+					t.Errorf("step %d: Acquire() = %s retry after %s, want %s retry after %s",
+						i, exceeded.Kind, exceeded.RetryAfter, s.wantKind, s.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalLimiterSweep(t *testing.T) {
+	limit := Limit{RequestsPerMinute: 60, TokensPerMinute: 100}
+	tests := []struct {
+		name    string
+		tokens  int
+		advance time.Duration
+		// wantKept 清理后 tenant 的桶是否保留
+		wantKept bool
+	}{
+		{name: "refilled bucket removed", tokens: 10, advance: sweepInterval, wantKept: false},
+		{name: "overdrawn bucket kept", tokens: 250, advance: sweepInterval, wantKept: true},
+		{name: "no sweep before interval", tokens: 10, advance: sweepInterval / 2, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter()
+			// 首次调用触发一次清理，之后按间隔清理
+			if _, err := l.Acquire(context.Background(), "tenant", limit, tt.tokens); err != nil {
+				t.Fatal(err)
+			}
+			*clock = clock.Add(tt.advance)
+			if _, err := l.Acquire(context.Background(), "other", limit, 0); err != nil {
+				t.Fatal(err)
+			}
+			if _, kept := l.buckets["tenant"]; kept != tt.wantKept {
+				t.Errorf("bucket kept = %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
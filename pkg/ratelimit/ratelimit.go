@@ -0,0 +1,108 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit 实现按租户的请求数和 Token 数限流
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 限流模式
+const (
+	// ModeLocal 本网关实例内的令牌桶限流
+	ModeLocal = "local"
+	// ModeShared 基于 Metadata-Center 的多实例共享限流
+	ModeShared = "shared"
+)
+
+// 超限类型，与 OpenAI 限流错误的 type 字段一致
+const (
+	// KindRequests 超过每分钟请求数限制
+	KindRequests = "requests"
+	// KindTokens 超过每分钟 Token 数限制
+	KindTokens = "tokens"
+)
+
+// Limit 限流配额，0 表示不限制
+type Limit struct {
+	// RequestsPerMinute 每分钟请求数
+	RequestsPerMinute int
+	// TokensPerMinute 每分钟 Token 数
+	TokensPerMinute int
+}
+
+// ExceededError 超过限流配额
+type ExceededError struct {
+	// Kind 超限类型 (requests, tokens)
+	Kind string
+	// Limit 超限的配额
+	Limit int
+	// Requested 本次请求的用量
+	Requested int
+	// RetryAfter 建议的重试等待时间
+	RetryAfter time.Duration
+}
+
+// Error 实现 error 接口
+func (e *ExceededError) Error() string {
+	unit := "requests per min (RPM)"
+	if e.Kind == KindTokens {
+		unit = "tokens per min (TPM)"
+	}
+	return fmt.Sprintf("Rate limit reached for %s: Limit %d, Requested %d. Please try again in %s.",
+		unit, e.Limit, e.Requested, e.RetryAfter.Round(time.Millisecond))
+}
+
+// Ticket 一次预扣的限流配额，请求结束后用于结算
+type Ticket struct {
+	key    string
+	tokens int
+}
+
+// Tokens 预扣的 Token 数
+func (t *Ticket) Tokens() int {
+	return t.tokens
+}
+
+// Limiter 限流器
+// 请求路由前按预估 Token 数预扣配额，响应结束后按实际用量结算差额
+type Limiter interface {
+	// Acquire 预扣一个请求和 tokens 个 Token，超限时返回 *ExceededError
+	Acquire(ctx context.Context, key string, limit Limit, tokens int) (*Ticket, error)
+	// Settle 按实际 Token 数结算预扣的配额
+	Settle(ctx context.Context, ticket *Ticket, limit Limit, actualTokens int)
+}
+
+var limiters = make(map[string]Limiter)
+
+// RegisterLimiter 注册限流器
+func RegisterLimiter(mode string, limiter Limiter) {
+	limiters[mode] = limiter
+}
+
+// GetLimiter 获取限流器，未知模式返回 nil
+func GetLimiter(mode string) Limiter {
+	if mode == "" {
+		mode = ModeLocal
+	}
+	return limiters[mode]
+}
+
+// EstimateTokens 根据 Prompt 字节数估算输入 Token 数，按平均 4 字节一个 Token 计算
+func EstimateTokens(promptLength int) int {
+	return (promptLength + 3) / 4
+}
@@ -0,0 +1,75 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/metadata"
+)
+
+// sharedWindowTTL 共享计数窗口的过期时间，保留一个窗口用于结算跨窗口结束的请求
+const sharedWindowTTL = 2 * time.Minute
+
+func init() {
+	RegisterLimiter(ModeShared, &SharedLimiter{})
+}
+
+// SharedLimiter 基于 Metadata-Center 的一分钟固定窗口限流器
+// 多个网关实例共享同一组计数；Metadata-Center 不可用时放行请求
+type SharedLimiter struct{}
+
+// Acquire 实现 Limiter 接口
+func (l *SharedLimiter) Acquire(ctx context.Context, key string, limit Limit, tokens int) (*Ticket, error) {
+	now := time.Now()
+	start := now.Truncate(time.Minute)
+	windowKey := fmt.Sprintf("%s:%d", key, start.Unix())
+
+	client := metadata.GetClientOrNoop()
+	usage, err := client.IncrRateLimit(ctx, windowKey, sharedWindowTTL, 1, tokens)
+	if err != nil {
+		api.LogWarnf("shared rate limit unavailable, allow request, key=%s, err=%v", key, err)
+		return &Ticket{tokens: tokens}, nil
+	}
+
+	retryAfter := start.Add(time.Minute).Sub(now)
+	var exceeded *ExceededError
+	if limit.RequestsPerMinute > 0 && usage.Requests > limit.RequestsPerMinute {
+		exceeded = &ExceededError{Kind: KindRequests, Limit: limit.RequestsPerMinute, Requested: 1, RetryAfter: retryAfter}
+	} else if limit.TokensPerMinute > 0 && usage.Tokens > limit.TokensPerMinute && usage.Tokens > tokens {
+		// 窗口内的第一个请求即使超过配额也放行，避免大请求永远无法通过
+		exceeded = &ExceededError{Kind: KindTokens, Limit: limit.TokensPerMinute, Requested: tokens, RetryAfter: retryAfter}
+	}
+	if exceeded != nil {
+		// 被拒绝的请求不占用配额
+		_ = client.SettleRateLimit(ctx, windowKey, sharedWindowTTL, -1, -tokens)
+		return nil, exceeded
+	}
+
+	return &Ticket{key: windowKey, tokens: tokens}, nil
+}
+
+// Settle 实现 Limiter 接口
+func (l *SharedLimiter) Settle(ctx context.Context, ticket *Ticket, limit Limit, actualTokens int) {
+	delta := actualTokens - ticket.tokens
+	if ticket.key == "" || delta == 0 {
+		return
+	}
+	_ = metadata.GetClientOrNoop().SettleRateLimit(ctx, ticket.key, sharedWindowTTL, 0, delta)
+}
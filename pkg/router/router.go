@@ -0,0 +1,167 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package router 实现分类模型路由，调用小模型对 Prompt 分类以选择虚拟模型的实际模型
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/istio-llm-filter/pkg/completion"
+	"github.com/istio-llm-filter/pkg/ttlcache"
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+const (
+	// EnvCacheMaxEntries 分类结果缓存的最大条目数
+	EnvCacheMaxEntries = "AUTO_ROUTER_CACHE_MAX_ENTRIES"
+	// DefaultCacheMaxEntries 默认分类结果缓存的最大条目数
+	DefaultCacheMaxEntries = 10000
+
+	// Header 分类请求携带的请求头，后端可据此区分路由流量
+	Header = "x-llm-router"
+
+	// maxLabelTokens 分类回答的最大 Token 数
+	maxLabelTokens = 32
+)
+
+var (
+	globalRouter     *Router
+	globalRouterOnce sync.Once
+)
+
+// Request 分类请求
+type Request struct {
+	// TraceId 原请求的 Trace ID
+	TraceId string
+	// Host 分类模型集群中选中的主机
+	Host types.Host
+	// ModelName 分类模型名
+	ModelName string
+	// SystemPrompt 路由提示词
+	SystemPrompt string
+	// Prompt 待分类的 Prompt 文本
+	Prompt []byte
+	// Timeout 超时时间
+	Timeout time.Duration
+	// CacheKey 缓存键，为空时不缓存
+	CacheKey string
+	// CacheTTL 缓存时间
+	CacheTTL time.Duration
+}
+
+// Router 分类模型客户端，按缓存键缓存分类标签
+type Router struct {
+	cache *ttlcache.Cache[string]
+}
+
+// GetRouter 获取全局分类模型客户端
+func GetRouter() *Router {
+	globalRouterOnce.Do(func() {
+		globalRouter = &Router{
+			cache: ttlcache.New[string](cacheMaxEntries()),
+		}
+	})
+	return globalRouter
+}
+
+// CacheKey 按虚拟模型名和 Prompt 哈希生成缓存键
+func CacheKey(autoModel string, prompt []byte) string {
+	h := fnv.New64a()
+	h.Write(prompt)
+	return autoModel + "/" + strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Classify 调用分类模型，返回分类标签以及是否命中缓存
+func (r *Router) Classify(ctx context.Context, req *Request) (string, bool, error) {
+	if req.CacheKey != "" {
+		if label, ok := r.cache.Get(req.CacheKey); ok {
+			return label, true, nil
+		}
+	}
+
+	label, err := r.call(ctx, req)
+	if err != nil {
+		return "", false, err
+	}
+	if req.CacheKey != "" && req.CacheTTL > 0 {
+		r.cache.Set(req.CacheKey, label, req.CacheTTL)
+	}
+	return label, false, nil
+}
+
+// call 发送分类请求并解析分类标签
+func (r *Router) call(ctx context.Context, req *Request) (string, error) {
+	temperature := 0.0
+	resp, err := completion.GetClient().Call(ctx, &completion.Request{
+		TraceId:      req.TraceId,
+		Host:         req.Host,
+		Header:       Header,
+		HeaderValue:  "true",
+		ModelName:    req.ModelName,
+		SystemPrompt: req.SystemPrompt,
+		UserPrompt:   string(req.Prompt),
+		MaxTokens:    maxLabelTokens,
+		Temperature:  &temperature,
+		Timeout:      req.Timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	return parseLabel(resp.Content)
+}
+
+// parseLabel 解析分类模型的回答
+// 优先解析 {"label": "..."}，兼容 Markdown 代码块包裹的 JSON 和直接返回的标签文本
+func parseLabel(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.Trim(content, "`")
+	content = strings.TrimSpace(content)
+
+	if strings.HasPrefix(content, "{") {
+		var answer struct {
+			Label string `json:"label"`
+		}
+		if err := sonic.UnmarshalString(content, &answer); err != nil {
+			return "", fmt.Errorf("invalid classifier answer %q: %w", content, err)
+		}
+		content = answer.Label
+	}
+
+	label := strings.Trim(strings.TrimSpace(content), `"'.`)
+	if label == "" {
+		return "", errors.New("classifier returned empty label")
+	}
+	return label, nil
+}
+
+func cacheMaxEntries() int {
+	if v := os.Getenv(EnvCacheMaxEntries); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultCacheMaxEntries
+}
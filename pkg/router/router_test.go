@@ -0,0 +1,54 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"testing"
+)
+
+func TestParseLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "json object", content: `{"label": "code"}`, want: "code"},
+		{name: "json with surrounding whitespace", content: "\n  {\"label\":\"math\"}  \n", want: "math"},
+		{name: "json code block", content: "```json\n{\"label\": \"code\"}\n```", want: "code"},
+		{name: "plain code block", content: "```\n{\"label\": \"chat\"}\n```", want: "chat"},
+		{name: "extra json fields ignored", content: `{"label": "code", "reason": "python snippet"}`, want: "code"},
+		{name: "bare label", content: "code", want: "code"},
+		{name: "quoted label", content: `"code"`, want: "code"},
+		{name: "label with trailing period", content: "math.", want: "math"},
+		{name: "single quoted label", content: "'chat'", want: "chat"},
+		{name: "invalid json", content: `{"label": `, wantErr: true},
+		{name: "json without label", content: `{"category": "code"}`, wantErr: true},
+		{name: "empty answer", content: "  ", wantErr: true},
+		{name: "only punctuation", content: `"."`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLabel(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLabel(%q) error = %v, wantErr %v", tt.content, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLabel(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/ast"
+	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
+
+	"github.com/istio-llm-filter/pkg/types"
+)
+
+// roleUser 用户消息角色
+const roleUser = "user"
+
+// GetUserPrompt 获取最后一条用户消息的文本，多模态消息只拼接文本部分
+func (t *Transcoder) GetUserPrompt() string {
+	for i := len(t.request.Messages) - 1; i >= 0; i-- {
+		msg := t.request.Messages[i]
+		if msg.Role != roleUser {
+			continue
+		}
+		switch content := msg.Content.(type) {
+		case string:
+			return content
+		case []interface{}:
+			var sb strings.Builder
+			for _, part := range content {
+				if partMap, ok := part.(map[string]interface{}); ok && partMap["type"] == "text" {
+					if text, ok := partMap["text"].(string); ok {
+						sb.WriteString(text)
+					}
+				}
+			}
+			return sb.String()
+		}
+		return ""
+	}
+	return ""
+}
+
+// RewritePrompt 按链式处理结果改写请求体
+// 最后一条用户消息的文本替换为 userPrompt，多模态消息保留非文本部分；systemPrompts 作为 system 消息插入该消息之前
+// 返回改写后的 Prompt 上下文，用于重新计算 Prompt 哈希
+func (t *Transcoder) RewritePrompt(headers api.RequestHeaderMap, buffer api.BufferInstance, userPrompt string, systemPrompts []string) (*types.PromptMessageContext, error) {
+	root, err := sonic.Get(buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request body: %w", err)
+	}
+	messages := root.Get("messages")
+	nodes, err := messages.ArrayUseNode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse messages: %w", err)
+	}
+
+	last := -1
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if role, _ := nodes[i].Get("role").String(); role == roleUser {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return nil, errors.New("no user message to rewrite")
+	}
+
+	if userPrompt != t.GetUserPrompt() {
+		content, err := rewriteUserContent(nodes[last].Get("content"), userPrompt)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := nodes[last].Set("content", content); err != nil {
+			return nil, fmt.Errorf("failed to set content: %w", err)
+		}
+	}
+
+	rewritten := make([]ast.Node, 0, len(nodes)+len(systemPrompts))
+	rewritten = append(rewritten, nodes[:last]...)
+	for _, prompt := range systemPrompts {
+		rewritten = append(rewritten, ast.NewObject([]ast.Pair{
+			{Key: "role", Value: ast.NewString("system")},
+			{Key: "content", Value: ast.NewString(prompt)},
+		}))
+	}
+	rewritten = append(rewritten, nodes[last:]...)
+	if _, err := root.Set("messages", ast.NewArray(rewritten)); err != nil {
+		return nil, fmt.Errorf("failed to set messages: %w", err)
+	}
+
+	body, err := root.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request body: %w", err)
+	}
+	if err := buffer.Set(body); err != nil {
+		return nil, err
+	}
+	headers.Set("content-length", strconv.Itoa(len(body)))
+
+	// 重新解析改写后的请求，保持请求内容与请求体一致
+	t.request = ChatCompletionRequest{}
+	if err := sonic.Unmarshal(body, &t.request); err != nil {
+		return nil, fmt.Errorf("failed to parse rewritten request: %w", err)
+	}
+	promptCtx := t.extractPromptContext()
+	t.attributes = t.buildAttributes(promptCtx)
+	return promptCtx, nil
+}
+
+// rewriteUserContent 构建替换后的用户消息内容
+// 文本消息直接替换；多模态消息替换为一个文本部分，并保留图片等非文本部分
+func rewriteUserContent(content *ast.Node, text string) (ast.Node, error) {
+	if content.TypeSafe() != ast.V_ARRAY {
+		return ast.NewString(text), nil
+	}
+	parts, err := content.ArrayUseNode()
+	if err != nil {
+		return ast.Node{}, fmt.Errorf("failed to parse content: %w", err)
+	}
+	rewritten := []ast.Node{ast.NewObject([]ast.Pair{
+		{Key: "type", Value: ast.NewString("text")},
+		{Key: "text", Value: ast.NewString(text)},
+	})}
+	for _, part := range parts {
+		if typ, _ := part.Get("type").String(); typ != "text" {
+			rewritten = append(rewritten, part)
+		}
+	}
+	return ast.NewArray(rewritten), nil
+}
@@ -19,8 +19,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/ast"
 	"github.com/envoyproxy/envoy/contrib/golang/common/go/api"
 
 	"github.com/istio-llm-filter/pkg/config"
@@ -58,6 +61,17 @@ type ChatCompletionRequest struct {
 	TopP        *float64      `json:"top_p,omitempty"`
 	N           *int          `json:"n,omitempty"`
 	Stop        interface{}   `json:"stop,omitempty"`
+	User        string        `json:"user,omitempty"`
+
+	MaxCompletionTokens *int            `json:"max_completion_tokens,omitempty"`
+	Tools               []interface{}   `json:"tools,omitempty"`
+	Functions           []interface{}   `json:"functions,omitempty"`
+	ResponseFormat      *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat 响应格式
+type ResponseFormat struct {
+	Type string `json:"type"`
 }
 
 // ChatMessage 聊天消息
@@ -86,6 +100,8 @@ type Transcoder struct {
 
 	// 解析后的请求
 	request ChatCompletionRequest
+	// attributes 用于请求内容匹配的请求属性
+	attributes *config.RequestAttributes
 
 	// 请求上下文
 	modelName       string
@@ -105,7 +121,7 @@ func NewTranscoder(callbacks api.FilterCallbackHandler, cfg *config.LLMProxyConf
 }
 
 // GetRequestData 解析请求数据
-func (t *Transcoder) GetRequestData(headers api.RequestHeaderMap, data []byte) (*types.RequestData, error) {
+func (t *Transcoder) GetRequestData(headers api.RequestHeaderMap, data []byte, claims config.Claims) (*types.RequestData, error) {
 	// 解析 JSON 请求
 	if err := sonic.Unmarshal(data, &t.request); err != nil {
 		return nil, fmt.Errorf("failed to parse request: %w", err)
@@ -121,37 +137,79 @@ func (t *Transcoder) GetRequestData(headers api.RequestHeaderMap, data []byte) (
 
 	reqData := &types.RequestData{
 		ModelName: t.request.Model,
+		Env:       t.getEnv(headers),
 	}
 
-	// 查找模型映射规则
-	if t.config != nil && len(t.config.ModelMappings) > 0 {
-		tuples := config.GetModelMappings(t.config.ModelMappings, t.request.Model)
-		if len(tuples) == 0 {
-			return nil, fmt.Errorf("model %s not found in mapping rules", t.request.Model)
-		}
+	// 提取 Prompt 内容
+	reqData.PromptContext = t.extractPromptContext()
+	t.attributes = t.buildAttributes(reqData.PromptContext)
 
-		// 根据请求头选择规则
-		targetRule := config.GetCandidateRule(tuples, headers)
-		if targetRule == nil {
-			return nil, fmt.Errorf("no matching rule found for model %s", t.request.Model)
+	// 查找模型映射规则，虚拟模型由过滤器按分类器选择实际模型后再匹配
+	if t.config != nil && len(t.config.ModelMappings) > 0 && t.config.FindAutoModel(reqData.ModelName) == nil {
+		if err := t.mapRequest(reqData, headers, claims); err != nil {
+			return nil, err
 		}
+	}
 
-		t.modelName = targetRule.SceneName
-		reqData.SceneName = targetRule.SceneName
-		reqData.BackendProtocol = targetRule.Backend
-		reqData.Cluster = targetRule.Cluster
+	t.logItems.ModelName = reqData.ModelName
+	t.logItems.Split = reqData.Split
+	api.LogDebugf("OpenAI request parsed: model=%s, env=%s, cluster=%s, backend=%s",
+		reqData.ModelName, reqData.Env, reqData.Cluster, reqData.BackendProtocol)
 
-		// 构建负载均衡选项
-		reqData.LbOptions = buildLbOptions(targetRule)
+	return reqData, nil
+}
+
+// mapRequest 按模型名匹配路由规则，填充集群、后端和负载均衡选项
+func (t *Transcoder) mapRequest(reqData *types.RequestData, headers api.RequestHeaderMap, claims config.Claims) error {
+	match := t.config.MatchModelInEnv(reqData.Env, reqData.ModelName)
+	if match == nil {
+		return fmt.Errorf("model %s not found in mapping rules", reqData.ModelName)
 	}
 
-	// 提取 Prompt 内容
-	reqData.PromptContext = t.extractPromptContext()
+	// 根据请求头、JWT Claims 和请求内容选择规则，并展开模型名捕获组模板
+	in := &config.MatchInput{Headers: headers, Claims: claims, Request: t.attributes}
+	targetRule := match.ExpandRule(config.GetCandidateRule(match.Tuples, in))
+	if targetRule == nil {
+		return fmt.Errorf("no matching rule found for model %s", reqData.ModelName)
+	}
 
-	t.logItems.ModelName = reqData.ModelName
-	api.LogDebugf("OpenAI request parsed: model=%s, cluster=%s, backend=%s",
-		reqData.ModelName, reqData.Cluster, reqData.BackendProtocol)
+	reqData.ModelKey = match.Key
+	reqData.SessionKey = t.getSessionKey(headers)
 
+	// 按权重选择分流子集，同一会话总是选中同一子集
+	if targetRule.IsSplit() {
+		split := targetRule.ChooseSplit(reqData.SessionKey)
+		if split == nil {
+			return fmt.Errorf("no split available for model %s", reqData.ModelName)
+		}
+		targetRule = targetRule.WithSplit(split)
+		reqData.Split = split.Name
+	}
+
+	t.modelName = targetRule.SceneName
+	reqData.SceneName = targetRule.SceneName
+	reqData.ChainName = targetRule.ChainName
+	reqData.BackendProtocol = targetRule.Backend
+	reqData.Cluster = targetRule.Cluster
+
+	// 构建负载均衡选项
+	reqData.LbOptions = buildLbOptions(targetRule)
+	return nil
+}
+
+// RemapRequest 按另一个模型名重新匹配路由规则，用于降级到其他模型
+func (t *Transcoder) RemapRequest(headers api.RequestHeaderMap, modelName string, claims config.Claims) (*types.RequestData, error) {
+	if t.config == nil || len(t.config.ModelMappings) == 0 {
+		return nil, fmt.Errorf("model %s not found in mapping rules", modelName)
+	}
+
+	reqData := &types.RequestData{
+		ModelName: modelName,
+		Env:       t.getEnv(headers),
+	}
+	if err := t.mapRequest(reqData, headers, claims); err != nil {
+		return nil, err
+	}
 	return reqData, nil
 }
 
@@ -164,6 +222,13 @@ func (t *Transcoder) EncodeRequest(modelName, backendProtocol string, headers ap
 		IsStream: t.isStream,
 	}
 
+	// 转发的模型名与请求不同时（LoRA 适配器、降级模型）改写请求体
+	if modelName != "" && modelName != t.request.Model {
+		if err := rewriteModel(headers, buffer, modelName); err != nil {
+			return nil, err
+		}
+	}
+
 	// 对于 vLLM、SGLang、TensorRT 后端，直接转发原始请求
 	switch backendProtocol {
 	case BackendVLLM, BackendSGLang, BackendTensorRT:
@@ -175,6 +240,26 @@ func (t *Transcoder) EncodeRequest(modelName, backendProtocol string, headers ap
 	return reqCtx, nil
 }
 
+// rewriteModel 改写请求体中的模型名
+func rewriteModel(headers api.RequestHeaderMap, buffer api.BufferInstance, modelName string) error {
+	root, err := sonic.Get(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to parse request body: %w", err)
+	}
+	if _, err := root.Set("model", ast.NewString(modelName)); err != nil {
+		return fmt.Errorf("failed to set model: %w", err)
+	}
+	body, err := root.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+	if err := buffer.Set(body); err != nil {
+		return err
+	}
+	headers.Set("content-length", strconv.Itoa(len(body)))
+	return nil
+}
+
 // DecodeHeaders 解码响应头
 func (t *Transcoder) DecodeHeaders(headers api.ResponseHeaderMap) error {
 	// OpenAI 响应头通常不需要特殊处理
@@ -275,9 +360,72 @@ func (t *Transcoder) processStreamResponse(data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("stream error: %s", string(data))
 	}
 
+	// 开启 stream_options.include_usage 时，最后一个数据块包含 token 统计信息
+	if bytes.Contains(data, []byte(`"usage"`)) {
+		t.parseStreamUsage(data)
+	}
+
 	return data, nil
 }
 
+// parseStreamUsage 从 SSE 数据块中提取 token 统计信息
+func (t *Transcoder) parseStreamUsage(data []byte) {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		if !ok {
+			continue
+		}
+		var chunk struct {
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if err := sonic.Unmarshal(bytes.TrimSpace(payload), &chunk); err != nil || chunk.Usage == nil {
+			continue
+		}
+		t.logItems.InputTokens = chunk.Usage.PromptTokens
+		t.logItems.OutputTokens = chunk.Usage.CompletionTokens
+	}
+}
+
+// buildAttributes 构建用于请求内容匹配的请求属性
+func (t *Transcoder) buildAttributes(promptCtx *types.PromptMessageContext) *config.RequestAttributes {
+	attrs := &config.RequestAttributes{
+		HasImage:     promptCtx.IsVlModel,
+		PromptLength: len(promptCtx.PromptContent),
+		Stream:       t.request.Stream,
+		HasTools:     len(t.request.Tools) > 0 || len(t.request.Functions) > 0,
+	}
+	if t.request.MaxCompletionTokens != nil {
+		attrs.MaxTokens = *t.request.MaxCompletionTokens
+	} else if t.request.MaxTokens != nil {
+		attrs.MaxTokens = *t.request.MaxTokens
+	}
+	if t.request.ResponseFormat != nil {
+		attrs.ResponseFormat = t.request.ResponseFormat.Type
+	}
+	return attrs
+}
+
+// getSessionKey 获取会话标识
+// 优先使用会话标识请求头，其次使用 OpenAI user 字段
+func (t *Transcoder) getSessionKey(headers api.RequestHeaderMap) string {
+	if key, ok := headers.Get(t.config.GetSessionHeader()); ok && key != "" {
+		return key
+	}
+	return t.request.User
+}
+
+// getEnv 获取过滤器解析并写入请求头的环境标识
+func (t *Transcoder) getEnv(headers api.RequestHeaderMap) string {
+	if t.config == nil || t.config.Env == nil {
+		return ""
+	}
+	env, _ := headers.Get(t.config.Env.GetHeader())
+	return env
+}
+
 // buildLbOptions 构建负载均衡选项
 func buildLbOptions(rule *config.Rule) *types.LoadBalancerOptions {
 	if rule == nil {
@@ -286,14 +434,18 @@ func buildLbOptions(rule *config.Rule) *types.LoadBalancerOptions {
 
 	opts := &types.LoadBalancerOptions{
 		RouteName: rule.RouteName,
+		Priority:  rule.Priority,
 	}
 
-	// 提取 headers
-	if len(rule.Headers) > 0 {
-		opts.Headers = make(map[string]string)
-		for _, h := range rule.Headers {
-			opts.Headers[h.Key] = h.Value
+	// 提取精确匹配的 headers
+	for _, h := range rule.Headers {
+		if h.Value == "" || h.Invert {
+			continue
+		}
+		if opts.Headers == nil {
+			opts.Headers = make(map[string]string)
 		}
+		opts.Headers[h.Key] = h.Value
 	}
 
 	// 提取 subset 标签和 LoRA
@@ -302,6 +454,8 @@ func buildLbOptions(rule *config.Rule) *types.LoadBalancerOptions {
 		for _, s := range rule.Subset {
 			if s.Lora != "" {
 				opts.LoraID = s.Lora
+				opts.LoraPath = s.LoraPath
+				opts.LoraLoadTimeout = time.Duration(s.LoraLoadTimeoutMs) * time.Millisecond
 			}
 			for k, v := range s.Labels {
 				opts.Selector[k] = v
@@ -25,7 +25,18 @@ import (
 // Transcoder 定义协议转码器接口
 type Transcoder interface {
 	// GetRequestData 解析请求信息，提取模型名、Prompt 等
-	GetRequestData(headers api.RequestHeaderMap, data []byte) (*types.RequestData, error)
+	// claims 为已验证的 JWT Claims，用于规则匹配，未配置 jwt 时为 nil
+	GetRequestData(headers api.RequestHeaderMap, data []byte, claims config.Claims) (*types.RequestData, error)
+
+	// RemapRequest 按另一个模型名重新匹配路由规则，用于降级到其他模型
+	RemapRequest(headers api.RequestHeaderMap, modelName string, claims config.Claims) (*types.RequestData, error)
+
+	// GetUserPrompt 获取最后一条用户消息的文本，作为链式处理步骤的输入
+	GetUserPrompt() string
+
+	// RewritePrompt 按链式处理结果改写请求体，返回改写后的 Prompt 上下文
+	// 最后一条用户消息的文本替换为 userPrompt，systemPrompts 作为 system 消息插入该消息之前
+	RewritePrompt(headers api.RequestHeaderMap, buffer api.BufferInstance, userPrompt string, systemPrompts []string) (*types.PromptMessageContext, error)
 
 	// EncodeRequest 编码请求到后端协议格式
 	EncodeRequest(modelName, backendProtocol string, headers api.RequestHeaderMap, buffer api.BufferInstance) (*types.RequestContext, error)
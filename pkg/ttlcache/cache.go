@@ -0,0 +1,110 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ttlcache 实现带过期时间和最大条目数的 LRU 缓存
+package ttlcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// entry 缓存条目
+type entry[V any] struct {
+	key      string
+	value    V
+	expireAt time.Time
+}
+
+// Cache 带过期时间的 LRU 缓存
+// 每个条目有独立的过期时间，超过最大条目数时淘汰最久未访问的条目，读写均为 O(1)
+type Cache[V any] struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	lru        *list.List
+	maxEntries int
+	now        func() time.Time
+}
+
+// New 创建缓存，maxEntries 小于等于 0 时不限制条目数
+func New[V any](maxEntries int) *Cache[V] {
+	return &Cache[V]{
+		items:      make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// Get 获取未过期的缓存值，命中时标记为最近访问
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := elem.Value.(*entry[V])
+	if c.now().After(e.expireAt) {
+		c.remove(elem)
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set 写入缓存值，超过最大条目数时淘汰最久未访问的条目
+func (c *Cache[V]) Set(key string, value V, ttl time.Duration) {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry[V])
+		e.value = value
+		e.expireAt = now.Add(ttl)
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&entry[V]{key: key, value: value, expireAt: now.Add(ttl)})
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Delete 删除缓存值
+func (c *Cache[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len 获取缓存条目数，包含尚未淘汰的过期条目
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *Cache[V]) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*entry[V]).key)
+}
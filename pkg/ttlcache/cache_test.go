@@ -0,0 +1,114 @@
+// Copyright The AIGW Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	type op struct {
+		set     bool
+		key     string
+		value   int
+		ttl     time.Duration
+		advance time.Duration
+		want    int
+		wantOK  bool
+	}
+	tests := []struct {
+		name       string
+		maxEntries int
+		ops        []op
+		wantLen    int
+	}{
+		{
+			name:       "hit before expiry",
+			maxEntries: 2,
+			ops: []op{
+				{set: true, key: "a", value: 1, ttl: time.Minute},
+				{key: "a", advance: 30 * time.Second, want: 1, wantOK: true},
+			},
+			wantLen: 1,
+		},
+		{
+			name:       "miss after expiry removes entry",
+			maxEntries: 2,
+			ops: []op{
+				{set: true, key: "a", value: 1, ttl: time.Minute},
+				{key: "a", advance: 2 * time.Minute},
+			},
+			wantLen: 0,
+		},
+		{
+			name:       "evict least recently used",
+			maxEntries: 2,
+			ops: []op{
+				{set: true, key: "a", value: 1, ttl: time.Minute},
+				{set: true, key: "b", value: 2, ttl: time.Minute},
+				{key: "a", want: 1, wantOK: true},
+				{set: true, key: "c", value: 3, ttl: time.Minute},
+				{key: "b"},
+				{key: "a", want: 1, wantOK: true},
+				{key: "c", want: 3, wantOK: true},
+			},
+			wantLen: 2,
+		},
+		{
+			name:       "overwrite refreshes value and ttl",
+			maxEntries: 2,
+			ops: []op{
+				{set: true, key: "a", value: 1, ttl: time.Minute},
+				{set: true, key: "a", value: 2, ttl: 3 * time.Minute, advance: 2 * time.Minute},
+				{key: "a", advance: 2 * time.Minute, want: 2, wantOK: true},
+			},
+			wantLen: 1,
+		},
+		{
+			name:       "unbounded",
+			maxEntries: 0,
+			ops: []op{
+				{set: true, key: "a", value: 1, ttl: time.Minute},
+				{set: true, key: "b", value: 2, ttl: time.Minute},
+				{set: true, key: "c", value: 3, ttl: time.Minute},
+				{key: "a", want: 1, wantOK: true},
+			},
+			wantLen: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			c := New[int](tt.maxEntries)
+			c.now = func() time.Time { return now }
+			for i, o := range tt.ops {
+				now = now.Add(o.advance)
+				if o.set {
+					c.Set(o.key, o.value, o.ttl)
+					continue
+				}
+				got, ok := c.Get(o.key)
+				if got != o.want || ok != o.wantOK {
+					t.Errorf("op %d: Get(%q) = %d, %v, want %d, %v", i, o.key, got, ok, o.want, o.wantOK)
+				}
+			}
+			if got := c.Len(); got != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", got, tt.wantLen)
+			}
+		})
+	}
+}
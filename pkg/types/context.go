@@ -30,8 +30,36 @@ const (
 	KeyPromptHash LBCtxKey = "lb.promptHash"
 	// KeyHostMatchInfo 主机匹配信息
 	KeyHostMatchInfo LBCtxKey = "lb.hostMatchInfo"
+	// KeyPromptLength Prompt 长度
+	KeyPromptLength LBCtxKey = "lb.promptLength"
 	// KeyLbSelector 负载均衡选择器标签
 	KeyLbSelector LBCtxKey = "lb.selector"
+	// KeySelectStrategy 候选主机选择策略
+	KeySelectStrategy LBCtxKey = "lb.select_strategy"
+	// KeySoftmaxTemperature softmax 选择策略温度
+	KeySoftmaxTemperature LBCtxKey = "lb.softmax_temperature"
+	// KeyScoreGap score_gap 选择策略的评分差距阈值
+	KeyScoreGap LBCtxKey = "lb.score_gap"
+	// KeyMinCandidates 最小候选数量
+	KeyMinCandidates LBCtxKey = "lb.min_candidates"
+	// KeyHostQueueDepth 主机饱和的未完成请求数阈值
+	KeyHostQueueDepth LBCtxKey = "lb.host_queue_depth"
+	// KeyShedQueueDepth 丢弃当前优先级请求的主机未完成请求数阈值
+	KeyShedQueueDepth LBCtxKey = "lb.shed_queue_depth"
+	// KeySLOTTFT TTFT SLO (time.Duration)
+	KeySLOTTFT LBCtxKey = "lb.slo_ttft"
+	// KeySLOTPOT TPOT SLO (time.Duration)
+	KeySLOTPOT LBCtxKey = "lb.slo_tpot"
+	// KeyLbPipeline 负载均衡插件流水线配置
+	KeyLbPipeline LBCtxKey = "lb.pipeline"
+	// KeyLoraID 请求的 LoRA 适配器 ID
+	KeyLoraID LBCtxKey = "lb.loraId"
+	// KeyAffinityKey 会话亲和的会话标识，为空时不启用会话亲和
+	KeyAffinityKey LBCtxKey = "lb.affinity_key"
+	// KeyAffinityMaxQueueDepth 亲和主机的未完成请求数上限
+	KeyAffinityMaxQueueDepth LBCtxKey = "lb.affinity_max_queue_depth"
+	// KeyAffinityTTL 会话亲和记录的有效期 (time.Duration)
+	KeyAffinityTTL LBCtxKey = "lb.affinity_ttl"
 
 	// KeyLoadAwareEnable 是否启用负载感知
 	KeyLoadAwareEnable LBCtxKey = "lb.load_aware_enable"
@@ -45,6 +73,16 @@ const (
 	KeyLoadRequestWeight LBCtxKey = "lb.request_load_weight"
 	// KeyLoadPrefillWeight Prefill 负载权重
 	KeyLoadPrefillWeight LBCtxKey = "lb.prefill_load_weight"
+	// KeyKVUsageWeight KV-Cache 使用率惩罚权重
+	KeyKVUsageWeight LBCtxKey = "lb.kv_usage_weight"
+	// KeyKVUsageThreshold KV-Cache 使用率排除阈值
+	KeyKVUsageThreshold LBCtxKey = "lb.kv_usage_threshold"
+	// KeyLoraAffinityWeight LoRA 适配器亲和权重
+	KeyLoraAffinityWeight LBCtxKey = "lb.lora_affinity_weight"
+	// KeyMaxAdaptersPerHost 单主机最多加载的 LoRA 适配器数量
+	KeyMaxAdaptersPerHost LBCtxKey = "lb.max_adapters_per_host"
+	// KeyLoraPollInterval 轮询后端已加载 LoRA 适配器的间隔
+	KeyLoraPollInterval LBCtxKey = "lb.lora_poll_interval"
 )
 
 // 日志字段键定义
@@ -69,12 +107,73 @@ const (
 	DefaultPrefillLoadWeight = 3
 	// DefaultCandidatePercent 默认候选集百分比
 	DefaultCandidatePercent = 5
+	// DefaultKVUsageWeight 默认 KV-Cache 使用率惩罚权重
+	DefaultKVUsageWeight = 2
+	// DefaultKVUsageThreshold 默认 KV-Cache 使用率排除阈值，0 表示不排除
+	DefaultKVUsageThreshold = 0
+	// DefaultLoraAffinityWeight 默认 LoRA 适配器亲和权重
+	DefaultLoraAffinityWeight = 3
+	// DefaultMaxAdaptersPerHost 默认单主机最多加载的 LoRA 适配器数量，0 表示不限制
+	DefaultMaxAdaptersPerHost = 0
+	// DefaultAffinityMaxQueueDepth 默认亲和主机的未完成请求数上限
+	DefaultAffinityMaxQueueDepth = 16
 )
 
+// 候选主机选择策略
+const (
+	// SelectTopPercent 在 Top N% 候选集中均匀随机选择
+	SelectTopPercent = "top_percent"
+	// SelectScoreGap 在与最高分差距不超过阈值的候选集中均匀随机选择
+	SelectScoreGap = "score_gap"
+	// SelectSoftmax 在通过过滤的全部主机中按评分 softmax 概率选择
+	SelectSoftmax = "softmax"
+)
+
+// 默认选择策略配置
+const (
+	// DefaultSelectStrategy 默认选择策略
+	DefaultSelectStrategy = SelectTopPercent
+	// DefaultSoftmaxTemperature 默认 softmax 温度
+	DefaultSoftmaxTemperature = 1.0
+	// DefaultScoreGap 默认评分差距阈值
+	DefaultScoreGap = 0.5
+	// DefaultMinCandidates 默认最小候选数量
+	DefaultMinCandidates = 1
+)
+
+// LbPluginConfig 负载均衡插件配置
+type LbPluginConfig struct {
+	// Name 插件名称
+	Name string `json:"name"`
+	// Weight 评分插件权重，过滤插件忽略
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// LbPipelineConfig 负载均衡插件流水线配置
+type LbPipelineConfig struct {
+	// Filters 按顺序执行的主机过滤插件
+	Filters []*LbPluginConfig `json:"filters,omitempty"`
+	// Scorers 加权求和的主机评分插件
+	Scorers []*LbPluginConfig `json:"scorers,omitempty"`
+}
+
 // HostMatchInfo 主机匹配信息
+// 由负载均衡器在选中主机后填充，供 Filter 使用
 type HostMatchInfo struct {
 	// CacheRatio 缓存命中率
 	CacheRatio float64 `json:"cache_ratio"`
+	// QueueDepth 选中时主机的未完成请求数
+	QueueDepth int `json:"queue_depth"`
+	// PredictedTTFTMs 选中时预测的 TTFT（毫秒）
+	PredictedTTFTMs float64 `json:"predicted_ttft_ms"`
+	// SLOUnmet 没有主机能满足 SLO
+	SLOUnmet bool `json:"slo_unmet"`
+	// Saturated 所有主机的未完成请求数都达到饱和阈值
+	Saturated bool `json:"saturated"`
+	// Overloaded 所有主机的未完成请求数都达到当前优先级的丢弃阈值
+	Overloaded bool `json:"overloaded"`
+	// Affinity 按会话亲和选中主机
+	Affinity bool `json:"affinity"`
 }
 
 // GetValueFromCtx 从 Context 中获取值，如果不存在则返回默认值
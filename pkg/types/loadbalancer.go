@@ -38,6 +38,8 @@ const (
 	RoundRobin LoadBalancerType = "RoundRobin"
 	// InferenceLB 推理负载均衡（多维度评分）
 	InferenceLB LoadBalancerType = "inference_lb"
+	// PDDisaggLB Prefill/Decode 分离负载均衡
+	PDDisaggLB LoadBalancerType = "pd_disagg"
 )
 
 // Host 表示一个后端服务实例
@@ -64,5 +66,12 @@ type LoadBalancer interface {
 	ChooseHost(ctx context.Context) Host
 }
 
+// PairLoadBalancer Prefill/Decode 分离负载均衡器，同时选择 Prefill 和 Decode 主机
+type PairLoadBalancer interface {
+	LoadBalancer
+	// ChoosePair 选择 Prefill 主机和 Decode 主机
+	ChoosePair(ctx context.Context) (prefill Host, decode Host)
+}
+
 // LoadBalancerFactory 负载均衡器工厂函数类型
 type LoadBalancerFactory func(ctx context.Context, hosts []Host) LoadBalancer
@@ -17,6 +17,7 @@ package types
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // EndpointStats 表示后端端点的负载统计信息
@@ -27,6 +28,10 @@ type EndpointStats struct {
 	TotalReqs int `json:"total_reqs"`
 	// PromptLength 当前正在处理的 prompt 总长度
 	PromptLength int `json:"prompt_length"`
+	// KVUsage KV-Cache 块使用率 (0-1)
+	KVUsage float64 `json:"kv_usage"`
+	// LoraAdapters 已加载的 LoRA 适配器列表
+	LoraAdapters []string `json:"lora_adapters,omitempty"`
 }
 
 // String 返回 EndpointStats 的 JSON 字符串表示
@@ -73,8 +78,39 @@ type KVCacheIndexer interface {
 	QueryKVCache(ctx context.Context, cluster string, promptHash []uint64, topK int) ([]*KVCacheLocation, error)
 }
 
+// RateLimitUsage 限流窗口内的累计用量
+type RateLimitUsage struct {
+	// Requests 窗口内的请求数
+	Requests int `json:"requests"`
+	// Tokens 窗口内的 Token 数
+	Tokens int `json:"tokens"`
+}
+
+// RateLimitCounter 定义共享限流计数接口
+type RateLimitCounter interface {
+	// IncrRateLimit 增加限流窗口内的请求数和 Token 数（同步），返回增加后的累计用量
+	// key: 限流窗口标识，同一窗口内的请求使用相同的 key
+	// ttl: 窗口过期时间
+	IncrRateLimit(ctx context.Context, key string, ttl time.Duration, requests, tokens int) (*RateLimitUsage, error)
+
+	// SettleRateLimit 修正限流窗口内的请求数和 Token 数（异步），用于按实际用量结算
+	SettleRateLimit(ctx context.Context, key string, ttl time.Duration, requests, tokens int) error
+}
+
+// QuotaCounter 定义共享额度计数接口
+type QuotaCounter interface {
+	// GetQuotaUsage 查询已使用的额度（同步）
+	GetQuotaUsage(ctx context.Context, key string) (float64, error)
+
+	// AddQuotaUsage 增加已使用的额度（异步）
+	// ttl: 计数过期时间
+	AddQuotaUsage(ctx context.Context, key string, amount float64, ttl time.Duration) error
+}
+
 // MetadataCenter 定义 Metadata-Center 完整接口
 type MetadataCenter interface {
 	InferenceLoadStats
 	KVCacheIndexer
+	RateLimitCounter
+	QuotaCounter
 }
@@ -14,14 +14,25 @@
 
 package types
 
+import "time"
+
 // RequestData 表示解析后的请求数据
 type RequestData struct {
 	// ModelName 客户端请求的模型名称
 	ModelName string
+	// ModelKey 匹配的 model_mapping_rule 键，用于查找模型的其他配置
+	// 通配或正则规则匹配时与 ModelName 不同
+	ModelKey string
+	// SessionKey 会话标识，来自会话标识请求头或 OpenAI user 字段
+	SessionKey string
+	// Split 按权重选中的分流子集名称
+	Split string
 	// SceneName 场景名称（路由规则映射后的名称）
 	SceneName string
 	// Env 环境标识
 	Env string
+	// ChainName 匹配规则引用的链式处理配置名称
+	ChainName string
 	// Cluster 目标集群名称
 	Cluster string
 	// BackendProtocol 后端协议类型 (vllm, sglang, triton 等)
@@ -36,8 +47,14 @@ type RequestData struct {
 type LoadBalancerOptions struct {
 	// RouteName 路由名称
 	RouteName string
+	// Priority 路由规则配置的优先级
+	Priority string
 	// LoraID LoRA 适配器 ID
 	LoraID string
+	// LoraPath LoRA 适配器源路径，为空时不动态加载
+	LoraPath string
+	// LoraLoadTimeout LoRA 适配器动态加载超时时间
+	LoraLoadTimeout time.Duration
 	// Headers 请求头匹配条件
 	Headers map[string]string
 	// Selector 标签选择器
@@ -52,6 +69,22 @@ func (o *LoadBalancerOptions) GetLoraID() string {
 	return o.LoraID
 }
 
+// GetPriority 获取请求优先级
+func (o *LoadBalancerOptions) GetPriority() string {
+	if o == nil {
+		return ""
+	}
+	return o.Priority
+}
+
+// GetLoraPath 获取 LoRA 适配器源路径
+func (o *LoadBalancerOptions) GetLoraPath() string {
+	if o == nil {
+		return ""
+	}
+	return o.LoraPath
+}
+
 // GetHeaderString 获取请求头字符串表示
 func (o *LoadBalancerOptions) GetHeaderString() string {
 	if o == nil || len(o.Headers) == 0 {
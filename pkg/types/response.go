@@ -31,12 +31,42 @@ type LLMErrorResponse struct {
 type LLMLogItems struct {
 	// ModelName 模型名称
 	ModelName string `json:"model_name,omitempty"`
+	// Split 按权重选中的分流子集名称
+	Split string `json:"split,omitempty"`
 	// InputTokens 输入 token 数
 	InputTokens int `json:"input_tokens,omitempty"`
 	// OutputTokens 输出 token 数
 	OutputTokens int `json:"output_tokens,omitempty"`
 	// ErrorMessage 错误消息
 	ErrorMessage string `json:"error_message,omitempty"`
+	// ChainSteps 链式处理各步骤的调用记录
+	ChainSteps []*ChainStepLog `json:"chain_steps,omitempty"`
+}
+
+// ChainStepLog 链式处理步骤的调用记录
+type ChainStepLog struct {
+	// Name 步骤名称
+	Name string `json:"name"`
+	// Model 步骤调用的模型
+	Model string `json:"model"`
+	// InputTokens 输入 token 数
+	InputTokens int `json:"input_tokens,omitempty"`
+	// OutputTokens 输出 token 数
+	OutputTokens int `json:"output_tokens,omitempty"`
+	// LatencyMs 调用耗时（毫秒）
+	LatencyMs int64 `json:"latency_ms"`
+	// Error 调用失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// TotalTokens 获取主模型与链式处理各步骤的 token 总数，用于限流结算和额度扣减
+func (l *LLMLogItems) TotalTokens() (int, int) {
+	inputTokens, outputTokens := l.InputTokens, l.OutputTokens
+	for _, step := range l.ChainSteps {
+		inputTokens += step.InputTokens
+		outputTokens += step.OutputTokens
+	}
+	return inputTokens, outputTokens
 }
 
 // SetErrorMessage 设置错误消息
@@ -68,6 +98,46 @@ var (
 		Type: "inference_server_error",
 		Msg:  "Inference Server Error",
 	}
+	ErrSLOUnmet = ErrCode{
+		Code: 429,
+		Type: "slo_unmet",
+		Msg:  "No Backend Can Meet The Latency SLO",
+	}
+	ErrLoraLoad = ErrCode{
+		Code: 503,
+		Type: "lora_load_error",
+		Msg:  "LoRA Adapter Load Error",
+	}
+	ErrLoraLoadTimeout = ErrCode{
+		Code: 504,
+		Type: "lora_load_timeout",
+		Msg:  "LoRA Adapter Load Timeout",
+	}
+	ErrQueueFull = ErrCode{
+		Code: 429,
+		Type: "queue_full",
+		Msg:  "Gateway Queue Is Full",
+	}
+	ErrQueueTimeout = ErrCode{
+		Code: 503,
+		Type: "queue_timeout",
+		Msg:  "Gateway Queue Wait Timeout",
+	}
+	ErrPriorityShed = ErrCode{
+		Code: 503,
+		Type: "priority_shed",
+		Msg:  "Request Shed Under Load By Priority",
+	}
+	ErrChainStep = ErrCode{
+		Code: 502,
+		Type: "chain_step_error",
+		Msg:  "Chain Step Error",
+	}
+	ErrChainStepTimeout = ErrCode{
+		Code: 504,
+		Type: "chain_step_timeout",
+		Msg:  "Chain Step Timeout",
+	}
 )
 
 // GatewayErrorResponse 网关错误响应
@@ -97,6 +167,32 @@ func FormatGatewayResponse(errCode *ErrCode, traceID, message string) []byte {
 	return data
 }
 
+// OpenAIErrorResponse OpenAI 格式错误响应
+type OpenAIErrorResponse struct {
+	Error *OpenAIError `json:"error"`
+}
+
+// OpenAIError OpenAI 格式错误详情
+type OpenAIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    string  `json:"code"`
+}
+
+// FormatOpenAIResponse 格式化 OpenAI 格式错误响应，兼容 OpenAI SDK 的错误处理和重试
+func FormatOpenAIResponse(errType, code, message string) []byte {
+	resp := &OpenAIErrorResponse{
+		Error: &OpenAIError{
+			Message: message,
+			Type:    errType,
+			Code:    code,
+		},
+	}
+	data, _ := json.Marshal(resp)
+	return data
+}
+
 // String 返回 ErrCode 的字符串表示
 func (e *ErrCode) String() string {
 	return fmt.Sprintf("code=%d, type=%s, msg=%s", e.Code, e.Type, e.Msg)